@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/flopp/socialrunclubs-de/internal/app"
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func main() {
+	// read config file from command line (e.g., config.json)
+	configFile := flag.String("config", "config.json", "Path to the config file")
+	geojsonFile := flag.String("geojson", "", "write the coverage gaps as GeoJSON to this file (optional)")
+	flag.Parse()
+
+	// load config from file
+	config := app.Config{}
+	if err := app.LoadConfig(*configFile, &config); err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	// get data from sheets
+	data, err := app.GetData(config)
+	if err != nil {
+		log.Fatalf("Error processing sheets: %v", err)
+	}
+
+	geocoder, err := app.NewGeocoder(config)
+	if err != nil {
+		log.Fatalf("Error creating geocoder: %v", err)
+	}
+	if err := app.AnnotateCityCoordinates(data, geocoder); err != nil {
+		log.Fatalf("Error annotating city coordinates: %v", err)
+	}
+	gazetteer, err := app.LoadGazetteer(config)
+	if err != nil {
+		log.Fatalf("Error loading gazetteer: %v", err)
+	}
+	if err := app.AnnotateCityMetadata(data, gazetteer); err != nil {
+		log.Fatalf("Error annotating city metadata: %v", err)
+	}
+	if err := app.AnnotateCityRegions(data, geocoder); err != nil {
+		log.Fatalf("Error annotating city regions: %v", err)
+	}
+
+	report := app.AnalyzeCoverage(data, gazetteer, config)
+
+	fmt.Printf("-- places with at least %s inhabitants without club within %.0f km: %d\n", report.MinPopulationLabel(), report.RadiusKM, len(report.Gaps))
+	for _, gap := range report.Gaps {
+		fmt.Printf("%-30s %-25s %12s  %s\n", gap.Name, gap.State, gap.PopulationLabel(), gap.NearestClubLabel())
+	}
+	fmt.Println("-- clubs per million inhabitants")
+	for _, state := range report.States {
+		fmt.Printf("%-25s %12s %4d %6s\n", state.Name, state.PopulationLabel(), state.Clubs, state.ClubsPerMillionLabel())
+	}
+
+	if *geojsonFile != "" {
+		geojson, err := report.GeoJSON()
+		if err != nil {
+			log.Fatalf("Error creating GeoJSON: %v", err)
+		}
+		if err := utils.WriteFileAtomic(*geojsonFile, geojson, 0644); err != nil {
+			log.Fatalf("Error writing GeoJSON: %v", err)
+		}
+		fmt.Printf("-- written %s\n", *geojsonFile)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"os"
 
 	"github.com/flopp/socialrunclubs-de/internal/app"
-	"github.com/flopp/socialrunclubs-de/internal/utils"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 )
@@ -80,13 +79,48 @@ func main() {
 	}
 
 	// annotate city coordinates
-	geocoder := utils.NewCachingGeocoder(utils.Download, fmt.Sprintf("%s/geocoder.json", config.CacheDir))
+	geocoder, err := app.NewGeocoder(config)
+	if err != nil {
+		log.Fatalf("Error creating geocoder: %v", err)
+	}
 	if err := app.AnnotateCityCoordinates(data, geocoder); err != nil {
 		log.Fatalf("Error annotating city coordinates: %v", err)
 	}
+	gazetteer, err := app.LoadGazetteer(config)
+	if err != nil {
+		log.Fatalf("Error loading gazetteer: %v", err)
+	}
+	if err := app.AnnotateCityMetadata(data, gazetteer); err != nil {
+		log.Fatalf("Error annotating city metadata: %v", err)
+	}
+	if err := app.CheckClubCoordinates(data, config); err != nil {
+		log.Fatalf("Error checking club coordinates: %v", err)
+	}
+	if err := app.AnnotateCityRegions(data, geocoder); err != nil {
+		log.Fatalf("Error annotating city regions: %v", err)
+	}
+	if err := app.AnnotateClubNeighbourhoods(data, config); err != nil {
+		log.Fatalf("Error annotating club neighbourhoods: %v", err)
+	}
 	if err := app.AnnotateNearestCities(data); err != nil {
 		log.Fatalf("Error annotating nearest cities: %v", err)
 	}
+	if err := app.AnnotateNearbyClubs(data, config); err != nil {
+		log.Fatalf("Error annotating nearby clubs: %v", err)
+	}
+	if err := app.AnnotateCityTags(data, config); err != nil {
+		log.Fatalf("Error annotating city tags: %v", err)
+	}
+	if err := app.GenerateContent(data); err != nil {
+		log.Fatalf("Error generating content: %v", err)
+	}
+
+	data.Coverage = app.AnalyzeCoverage(data, gazetteer, config)
+
+	// report data problems, they do not prevent rendering
+	for _, finding := range data.Findings {
+		log.Printf("finding: %s", finding)
+	}
 
 	// copy static files to output directory
 	cssFiles, jsFiles, err := app.CopyAssets(config)
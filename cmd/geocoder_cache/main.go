@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/flopp/socialrunclubs-de/internal/app"
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func main() {
+	// read config file from command line (e.g., config.json)
+	configFile := flag.String("config", "config.json", "Path to the config file")
+	invalidate := flag.String("invalidate", "", "comma separated list of queries (cities) to remove from the cache (optional)")
+	invalidateProvider := flag.String("invalidate-provider", "", "remove all entries of this provider, e.g. 'nominatim' (optional)")
+	invalidateStale := flag.Bool("invalidate-stale", false, "remove all stale entries (optional)")
+	flag.Parse()
+
+	// load config from file
+	config := app.Config{}
+	if err := app.LoadConfig(*configFile, &config); err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	cache, err := app.LoadGeocoderCache(config)
+	if err != nil {
+		log.Fatalf("Error loading geocoder cache: %v", err)
+	}
+
+	queries := make(map[string]bool)
+	for _, query := range utils.SplitAndTrim(*invalidate, ",") {
+		queries[query] = true
+	}
+	if len(queries) > 0 || *invalidateProvider != "" || *invalidateStale {
+		removed, err := cache.Invalidate(func(entry utils.GeocoderCacheEntry) bool {
+			return queries[entry.Query] ||
+				(*invalidateProvider != "" && entry.Provider == *invalidateProvider) ||
+				(*invalidateStale && cache.IsStale(entry))
+		})
+		if err != nil {
+			log.Fatalf("Error saving geocoder cache: %v", err)
+		}
+		fmt.Printf("-- removed %d entries\n", removed)
+		return
+	}
+
+	// list entries
+	entries := cache.Entries()
+	for _, entry := range entries {
+		provider := entry.Provider
+		if provider == "" {
+			provider = "?"
+		}
+		fetched := "?"
+		if !entry.Time.IsZero() {
+			fetched = entry.Time.Format("2006-01-02")
+		}
+		stale := ""
+		if cache.IsStale(entry) {
+			stale = " (stale)"
+		}
+		fmt.Printf("%s: %.5f,%.5f provider=%s confidence=%.2f fetched=%s%s\n", entry.Query, entry.Lat, entry.Lon, provider, entry.Confidence, fetched, stale)
+	}
+	fmt.Printf("-- %d entries\n", len(entries))
+}
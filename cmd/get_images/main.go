@@ -4,45 +4,54 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/flopp/socialrunclubs-de/internal/app"
+	"github.com/flopp/socialrunclubs-de/internal/images"
+	"github.com/flopp/socialrunclubs-de/internal/imagesources"
 	"github.com/flopp/socialrunclubs-de/internal/utils"
 )
 
-func getStravaClubId(url string) (string, error) {
-	re := regexp.MustCompile(`^https?://www\.strava\.com/clubs/([^/]+)/?$`)
-	matches := re.FindStringSubmatch(url)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("invalid Strava club URL: %s", url)
+// sourceClub returns the club data needed by the image providers.
+func sourceClub(club *app.Club) imagesources.Club {
+	return imagesources.Club{
+		Slug:      strings.TrimPrefix(club.Slug(), "/"),
+		ImageURL:  club.ImageURL,
+		Instagram: club.Instagram,
+		Strava:    club.StravaClub,
+		Website:   club.Website,
 	}
-	return matches[1], nil
 }
 
-func extractStravaClubImageUrl(htmlFile string) (string, error) {
-	htmlBytes, err := os.ReadFile(htmlFile)
+// updateRecord imports the source image into the image directory and records its provenance
+func updateRecord(config app.Config, record *images.Record, src imagesources.Image, now time.Time) error {
+	targetImage := filepath.Join(config.ImageDir, record.Image)
+	size, err := images.Import(src.File, targetImage)
 	if err != nil {
-		return "", fmt.Errorf("error reading Strava club HTML file: %w", err)
+		return fmt.Errorf("importing image: %w", err)
 	}
-	htmlContent := string(htmlBytes)
-
-	reOgImage := regexp.MustCompile(`<meta property="og:image" content="([^"]+)"`)
-	matches := reOgImage.FindStringSubmatch(htmlContent)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("could not find og:image in Strava club HTML")
+	hash, err := utils.FileHash(targetImage)
+	if err != nil {
+		return fmt.Errorf("hashing image: %w", err)
 	}
 
-	imageURL := matches[1]
-	imageURL = strings.ReplaceAll(imageURL, "&amp;", "&")
-	return imageURL, nil
+	record.Source = src.Source
+	record.SourceURL = src.URL
+	record.FetchedAt = now
+	record.Hash = hash
+	record.Width = size.X
+	record.Height = size.Y
+	record.Error = ""
+	record.FailedAt = time.Time{}
+	return nil
 }
 
 func main() {
 	// read config file from command line (e.g., config.json)
 	configFile := flag.String("config", "config.json", "Path to the config file")
+	maxAge := flag.Duration("max-age", 30*24*time.Hour, "refresh images older than this (optional)")
 	flag.Parse()
 
 	delayMin := 2
@@ -60,97 +69,95 @@ func main() {
 		log.Fatalf("Error processing sheets: %v", err)
 	}
 
-	for _, item := range data.Clubs {
-		profileName := item.InstagramProfile()
-		if profileName != "" {
-			targetProfileHtml := config.CacheDir + "/instagram/" + profileName + "/html"
-			if !utils.FileExists(targetProfileHtml) {
-				err := utils.Download(item.Instagram, targetProfileHtml)
-				if err != nil {
-					log.Printf("Error downloading Instagram profile: %v", err)
-					break
-				}
-
-				// sleep for a random time between 1 and 3 seconds to avoid rate limiting
-				utils.RandomSleep(delayMin, delayMax)
-			}
-
-			targetProfileImage := config.CacheDir + "/instagram/" + profileName + "/image.jpg"
-			if !utils.FileExists(targetProfileImage) {
-				// get <meta property="og:image" content="([^"]+)" /> from the downloaded HTML
-				htmlBytes, err := os.ReadFile(targetProfileHtml)
-				if err != nil {
-					log.Printf("Error reading HTML file: %v", err)
-					continue
-				}
-				htmlContent := string(htmlBytes)
-
-				reOgImage := regexp.MustCompile(`<meta property="og:image" content="([^"]+)"`)
-				matches := reOgImage.FindStringSubmatch(htmlContent)
-				if len(matches) < 2 {
-					log.Printf("Could not find og:image in profile HTML for %s", profileName)
-					continue
-				}
-
-				imageURL := matches[1]
-				imageURL = strings.ReplaceAll(imageURL, "&amp;", "&")
-				err = utils.Download(imageURL, targetProfileImage)
-				if err != nil {
-					log.Printf("Error downloading Instagram profile image: %v", err)
-					continue
-				}
-			}
+	order := config.Images.Sources
+	if len(order) == 0 {
+		order = imagesources.DefaultOrder
+	}
+	// sleep for a random time after each download to avoid rate limiting
+	sleepyDownload := func(download func(url string, targetPath string) error) func(url string, targetPath string) error {
+		return func(url string, targetPath string) error {
+			defer utils.RandomSleep(delayMin, delayMax)
+			return download(url, targetPath)
+		}
+	}
+	providers, err := imagesources.New(imagesources.DefaultOrder, imagesources.Options{
+		Download:      sleepyDownload(utils.Download),
+		DownloadAgent: sleepyDownload(utils.DownloadAgent),
+		CacheDir:      config.CacheDir,
+		ImageDir:      "club-images",
+	})
+	if err != nil {
+		log.Fatalf("Error creating image providers: %v", err)
+	}
+	if _, err := imagesources.Order(providers, order); err != nil {
+		log.Printf("Invalid image source order in config: %v", err)
+		order = imagesources.DefaultOrder
+	}
 
-			targetImage := config.ImageDir + "/" + profileName + ".jpg"
-			if err := utils.CopyFile(targetProfileImage, targetImage); err != nil {
-				log.Printf("Error copying Instagram profile image to target image: %v", err)
-			}
+	manifestFile := filepath.Join(config.ImageDir, "images.json")
+	manifest, err := images.LoadManifest(manifestFile)
+	if err != nil {
+		log.Fatalf("Error loading image manifest: %v", err)
+	}
 
+	now := time.Now()
+	for _, item := range data.Clubs {
+		imageName := filepath.Join(item.City.SanitizeName(), item.SanitizeName()+".jpg")
+		record, found := manifest[item.Slug()]
+		if !found || record.Image != imageName {
+			record = &images.Record{Image: imageName}
+			manifest[item.Slug()] = record
+		}
+		if utils.FileExists(filepath.Join(config.ImageDir, imageName)) && !record.IsStale(now, *maxAge) {
 			continue
 		}
 
-		stravaClubId, err := getStravaClubId(item.StravaClub)
-		if err == nil {
-			targetStravaHtml := config.CacheDir + "/strava/" + stravaClubId + "/html"
-			if !utils.FileExists(targetStravaHtml) {
-				err := utils.DownloadAgent(item.StravaClub, targetStravaHtml)
-				if err != nil {
-					log.Printf("Error downloading Strava club HTML: %v", err)
-				}
-			}
+		clubOrder := order
+		if len(item.ImageSources) > 0 {
+			clubOrder = item.ImageSources
+		}
 
-			imageUrl, err := extractStravaClubImageUrl(targetStravaHtml)
-			if err != nil {
-				log.Printf("Error extracting Strava club image URL: %v", err)
-				continue
-			}
+		clubProviders, err := imagesources.Order(providers, clubOrder)
+		if err != nil {
+			log.Printf("Invalid IMAGE_SOURCES of club %s in city %s: %v", item.Name, item.City.Name, err)
+		}
 
-			targetStravaImage := config.CacheDir + "/strava/" + stravaClubId + "/image.jpg"
-			if !utils.FileExists(targetStravaImage) {
-				err = utils.DownloadAgent(imageUrl, targetStravaImage)
-				if err != nil {
-					log.Printf("Error downloading Strava club image: %v", err)
-					continue
-				}
-			}
+		// re-download cached files if the image has been fetched (or tried) before
+		refresh := found
+		src, err := imagesources.Find(clubProviders, sourceClub(item), refresh)
+		if err == nil {
+			err = updateRecord(config, record, src, now)
+		}
+		if err != nil {
+			log.Printf("Error fetching image for club %s in city %s: %v", item.Name, item.City.Name, err)
+			record.Error = err.Error()
+			record.FailedAt = now
+		}
 
-			targetImage := filepath.Join(config.ImageDir, item.City.SanitizeName(), item.SanitizeName()+".jpg")
-			if err := utils.CopyFile(targetStravaImage, targetImage); err != nil {
-				log.Printf("Error copying Strava club image to target image: %v", err)
-			}
+		// save after each club, so an interrupted run keeps its progress
+		if err := manifest.Save(manifestFile); err != nil {
+			log.Fatalf("Error saving image manifest: %v", err)
+		}
+	}
 
-			continue
+	// report
+	failed := manifest.Failed()
+	if len(failed) > 0 {
+		fmt.Printf("-- %d failed image fetches:\n", len(failed))
+		for _, slug := range failed {
+			fmt.Printf("%s: %s\n", slug, manifest[slug].Error)
 		}
+	}
 
-		directImage := filepath.Join("club-images", item.City.SanitizeName(), item.SanitizeName()+".jpg")
-		if utils.FileExists(directImage) {
-			targetImage := filepath.Join(config.ImageDir, item.City.SanitizeName(), item.SanitizeName()+".jpg")
-			if err := utils.CopyFile(directImage, targetImage); err != nil {
-				log.Printf("Error copying direct image to target image: %v", err)
+	placeholder := 0
+	for _, item := range data.Clubs {
+		if app.FindClubImage(config, item) == "" {
+			if placeholder == 0 {
+				fmt.Println("-- clubs without image (using placeholder):")
 			}
-			continue
+			placeholder++
+			fmt.Printf("%s (%s)\n", item.Name, item.Slug())
 		}
-
-		log.Printf("No image found for club %s in city %s -> %s", item.Name, item.City.Name, directImage)
 	}
+	fmt.Printf("-- %d/%d clubs without image\n", placeholder, len(data.Clubs))
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/flopp/socialrunclubs-de/internal/app"
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func main() {
+	inFile := flag.String("in", "", "CSV extract of German municipalities, e.g. the result of scripts/gemeinden.sparql")
+	outFile := flag.String("out", "data/gemeinden.csv", "municipality list used as gazetteer")
+	flag.Parse()
+
+	if *inFile == "" {
+		log.Fatal("Missing input file (-in)")
+	}
+	file, err := os.Open(*inFile)
+	if err != nil {
+		log.Fatalf("Error opening input file: %v", err)
+	}
+	defer file.Close()
+
+	entries, skipped, err := app.ImportMunicipalities(file)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *inFile, err)
+	}
+	if err := utils.WriteGazetteer(*outFile, entries); err != nil {
+		log.Fatalf("Error writing %s: %v", *outFile, err)
+	}
+	fmt.Printf("-- imported %d municipalities to %s (%d rows skipped)\n", len(entries), *outFile, skipped)
+}
@@ -3,12 +3,15 @@ module github.com/flopp/socialrunclubs-de
 go 1.26.2
 
 require (
-	github.com/codingsince1985/geo-golang v1.9.0
+	github.com/BurntSushi/toml v1.6.0
 	github.com/flopp/go-coordsparser v0.0.0-20250311184423-61a7ff62d17c
 	github.com/flopp/go-filehash v0.0.0-20250313113005-e3e8650a2258
 	github.com/flopp/go-googlesheetswrapper v0.0.0-20260406112809-7c5a6afecd10
+	github.com/yuin/goldmark v1.8.2
+	golang.org/x/image v0.25.0
 	golang.org/x/text v0.40.0
 	google.golang.org/api v0.289.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
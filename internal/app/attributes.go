@@ -0,0 +1,244 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// AttributeValue is a value of a controlled vocabulary, e.g. a language or an
+// audience; the sheet may contain the key, the label or one of the aliases.
+type AttributeValue struct {
+	Key     string
+	Label   string
+	aliases []string
+}
+
+var languageValues = []AttributeValue{
+	{"de", "Deutsch", []string{"german", "deutsch"}},
+	{"en", "Englisch", []string{"english"}},
+	{"es", "Spanisch", []string{"spanish", "espanol"}},
+	{"fr", "Französisch", []string{"french", "francais"}},
+	{"it", "Italienisch", []string{"italian", "italiano"}},
+	{"nl", "Niederländisch", []string{"dutch"}},
+	{"pl", "Polnisch", []string{"polish"}},
+	{"pt", "Portugiesisch", []string{"portuguese"}},
+	{"tr", "Türkisch", []string{"turkish"}},
+	{"uk", "Ukrainisch", []string{"ukrainian"}},
+	{"ar", "Arabisch", []string{"arabic"}},
+}
+
+var costValues = []AttributeValue{
+	{"free", "kostenlos", []string{"gratis", "umsonst", "0"}},
+	{"donation", "auf Spendenbasis", []string{"spende", "spenden", "spendenbasiert"}},
+	{"paid", "kostenpflichtig", []string{"mitgliedsbeitrag", "beitrag"}},
+}
+
+var audienceValues = []AttributeValue{
+	{"women", "nur Frauen", []string{"frauen", "female", "women only"}},
+	{"flinta", "FLINTA*", []string{"flinta only"}},
+	{"lgbtq", "LGBTQ+", []string{"lgbtqia", "queer"}},
+	{"students", "Studierende", []string{"studenten", "studis", "uni"}},
+}
+
+// lookupAttribute finds the value by key, label or alias (ignoring case, umlauts
+// and punctuation, see utils.SanitizeName).
+func lookupAttribute(values []AttributeValue, raw string) (AttributeValue, bool) {
+	key := utils.SanitizeName(raw)
+	for _, value := range values {
+		if key == value.Key || key == utils.SanitizeName(value.Label) {
+			return value, true
+		}
+		for _, alias := range value.aliases {
+			if key == utils.SanitizeName(alias) {
+				return value, true
+			}
+		}
+	}
+	return AttributeValue{}, false
+}
+
+func parseAttributeValues(column string, values []AttributeValue, raw string) ([]AttributeValue, error) {
+	var result []AttributeValue
+	for _, item := range utils.SplitAndTrim(raw, ",") {
+		value, found := lookupAttribute(values, item)
+		if !found {
+			return nil, fmt.Errorf("invalid %s: %q", column, item)
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// PaceRange is the pace of a pace group in seconds per kilometer.
+type PaceRange struct {
+	Min int
+	Max int // equals Min for a single pace
+}
+
+func formatPace(seconds int) string {
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Label returns the pace like "5:30–6:30 min/km".
+func (p PaceRange) Label() string {
+	if p.Min == p.Max {
+		return formatPace(p.Min) + " min/km"
+	}
+	return fmt.Sprintf("%s–%s min/km", formatPace(p.Min), formatPace(p.Max))
+}
+
+const (
+	minPace = 2*60 + 30
+	maxPace = 12 * 60
+	// pace groups faster/slower than these paces match the "fast"/"relaxed" facets
+	fastPace    = 5 * 60
+	relaxedPace = 6*60 + 30
+)
+
+var rePace = regexp.MustCompile(`^(\d{1,2})[:.](\d{2})$`)
+
+func parsePace(s string) (int, error) {
+	match := rePace.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, fmt.Errorf("invalid pace: %q", s)
+	}
+	minutes, _ := strconv.Atoi(match[1])
+	seconds, _ := strconv.Atoi(match[2])
+	pace := minutes*60 + seconds
+	if seconds > 59 || pace < minPace || pace > maxPace {
+		return 0, fmt.Errorf("invalid pace: %q", s)
+	}
+	return pace, nil
+}
+
+// parsePaces parses the PACE column, e.g. "5:00-5:30, 6:30 min/km"; each item
+// is a pace group.
+func parsePaces(raw string) ([]PaceRange, error) {
+	var paces []PaceRange
+	for _, item := range utils.SplitAndTrim(strings.ToLower(raw), ",") {
+		item = strings.TrimSpace(strings.TrimSuffix(item, "min/km"))
+		minRaw, maxRaw, isRange := strings.Cut(strings.ReplaceAll(item, "–", "-"), "-")
+		p, err := parsePace(minRaw)
+		if err != nil {
+			return nil, err
+		}
+		pace := PaceRange{p, p}
+		if isRange {
+			if pace.Max, err = parsePace(maxRaw); err != nil {
+				return nil, err
+			}
+			if pace.Max < pace.Min {
+				pace.Min, pace.Max = pace.Max, pace.Min
+			}
+		}
+		paces = append(paces, pace)
+	}
+	return paces, nil
+}
+
+const maxDistance = 50.0
+
+func parseKilometers(s string) (float64, error) {
+	km, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+	if err != nil || km <= 0 || km > maxDistance {
+		return 0, fmt.Errorf("invalid distance: %q", s)
+	}
+	return km, nil
+}
+
+// parseDistance parses the DISTANCE column, e.g. "5 km", "5-8" or "7,5".
+func parseDistance(raw string) (minKm, maxKm float64, err error) {
+	raw = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.ToLower(raw)), "km"))
+	if raw == "" {
+		return 0, 0, nil
+	}
+	minRaw, maxRaw, isRange := strings.Cut(strings.ReplaceAll(raw, "–", "-"), "-")
+	if minKm, err = parseKilometers(minRaw); err != nil {
+		return 0, 0, fmt.Errorf("invalid distance: %q", raw)
+	}
+	maxKm = minKm
+	if isRange {
+		if maxKm, err = parseKilometers(maxRaw); err != nil || maxKm < minKm {
+			return 0, 0, fmt.Errorf("invalid distance: %q", raw)
+		}
+	}
+	return minKm, maxKm, nil
+}
+
+// ClubAttributes are the optional facts of a club from the PACE, DISTANCE,
+// LANGUAGES, COST and AUDIENCE columns of the CLUBS sheet.
+type ClubAttributes struct {
+	Paces       []PaceRange // pace groups
+	DistanceMin float64     // typical distance in km; 0 if unknown
+	DistanceMax float64
+	Languages   []AttributeValue
+	Cost        *AttributeValue  // nil if unknown
+	Audiences   []AttributeValue // empty for everyone
+}
+
+// parseClubAttributes parses the attribute columns; invalid columns are
+// reported as errors and left empty.
+func parseClubAttributes(paceRaw, distanceRaw, languagesRaw, costRaw, audienceRaw string) (ClubAttributes, []error) {
+	var attributes ClubAttributes
+	var errs []error
+	var err error
+	if attributes.Paces, err = parsePaces(paceRaw); err != nil {
+		errs = append(errs, err)
+	}
+	if attributes.DistanceMin, attributes.DistanceMax, err = parseDistance(distanceRaw); err != nil {
+		errs = append(errs, err)
+	}
+	if attributes.Languages, err = parseAttributeValues("language", languageValues, languagesRaw); err != nil {
+		errs = append(errs, err)
+	}
+	if costs, err := parseAttributeValues("cost", costValues, costRaw); err != nil {
+		errs = append(errs, err)
+	} else if len(costs) > 1 {
+		errs = append(errs, fmt.Errorf("more than one cost: %q", costRaw))
+	} else if len(costs) == 1 {
+		attributes.Cost = &costs[0]
+	}
+	if attributes.Audiences, err = parseAttributeValues("audience", audienceValues, audienceRaw); err != nil {
+		errs = append(errs, err)
+	}
+	return attributes, errs
+}
+
+func (a ClubAttributes) IsEmpty() bool {
+	return len(a.Paces) == 0 && a.DistanceMin == 0 && len(a.Languages) == 0 && a.Cost == nil && len(a.Audiences) == 0
+}
+
+func formatKilometers(km float64) string {
+	return strings.Replace(strconv.FormatFloat(km, 'f', -1, 64), ".", ",", 1)
+}
+
+// DistanceLabel returns the distance like "5–8 km", or "" if unknown.
+func (a ClubAttributes) DistanceLabel() string {
+	switch {
+	case a.DistanceMin == 0:
+		return ""
+	case a.DistanceMin == a.DistanceMax:
+		return formatKilometers(a.DistanceMin) + " km"
+	}
+	return fmt.Sprintf("%s–%s km", formatKilometers(a.DistanceMin), formatKilometers(a.DistanceMax))
+}
+
+func joinLabels(values []AttributeValue) string {
+	labels := make([]string, 0, len(values))
+	for _, value := range values {
+		labels = append(labels, value.Label)
+	}
+	return strings.Join(labels, ", ")
+}
+
+func (a ClubAttributes) LanguagesLabel() string {
+	return joinLabels(a.Languages)
+}
+
+func (a ClubAttributes) AudiencesLabel() string {
+	return joinLabels(a.Audiences)
+}
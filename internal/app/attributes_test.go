@@ -0,0 +1,50 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestParseClubAttributes(t *testing.T) {
+	attributes, errs := parseClubAttributes("5:00-5:30, 7:00–6:30 min/km", "7,5 - 10 km", "Deutsch, english", "Gratis", "FLINTA*, queer")
+	if len(errs) != 0 {
+		t.Fatalf("parseClubAttributes() errors = %v", errs)
+	}
+	if len(attributes.Paces) != 2 || attributes.Paces[0].Label() != "5:00–5:30 min/km" || attributes.Paces[1] != (PaceRange{390, 420}) {
+		t.Errorf("Paces = %v", attributes.Paces)
+	}
+	if label := attributes.DistanceLabel(); label != "7,5–10 km" {
+		t.Errorf("DistanceLabel() = %q", label)
+	}
+	if label := attributes.LanguagesLabel(); label != "Deutsch, Englisch" {
+		t.Errorf("LanguagesLabel() = %q", label)
+	}
+	if attributes.Cost == nil || attributes.Cost.Key != "free" {
+		t.Errorf("Cost = %v", attributes.Cost)
+	}
+	if label := attributes.AudiencesLabel(); label != "FLINTA*, LGBTQ+" {
+		t.Errorf("AudiencesLabel() = %q", label)
+	}
+
+	if attributes, errs := parseClubAttributes("", "", "", "", ""); len(errs) != 0 || !attributes.IsEmpty() {
+		t.Errorf("empty: %v, %v", attributes, errs)
+	}
+	if attributes, _ := parseClubAttributes("6:00", "5", "", "", ""); attributes.Paces[0].Label() != "6:00 min/km" || attributes.DistanceLabel() != "5 km" {
+		t.Errorf("single values: %v", attributes)
+	}
+
+	for _, tc := range [][5]string{
+		{"schnell", "", "", "", ""},
+		{"1:30", "", "", "", ""},
+		{"5:75", "", "", "", ""},
+		{"", "100", "", "", ""},
+		{"", "8-5", "", "", ""},
+		{"", "", "Klingonisch", "", ""},
+		{"", "", "", "kostenlos, Spende", ""},
+		{"", "", "", "teuer", ""},
+		{"", "", "", "", "Senioren"},
+	} {
+		if attributes, errs := parseClubAttributes(tc[0], tc[1], tc[2], tc[3], tc[4]); len(errs) != 1 || !attributes.IsEmpty() {
+			t.Errorf("parseClubAttributes(%q): %v, %v", tc, attributes, errs)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"encoding/json"
+	"html/template"
+)
+
+type Breadcrumb struct {
+	Name    string
+	Path    string
+	Current bool // the current page
+}
+
+// Breadcrumbs returns the region hierarchy of the page: state, city and club,
+// city tag or weekday. Districts (Landkreise) are left out, as they have no
+// pages of their own; they are part of the JSON-LD places, see cityPlace.
+func (t TemplateData) Breadcrumbs() []Breadcrumb {
+	var state *State
+	var city *City
+	switch {
+	case t.Club != nil:
+		city = t.Club.City
+		state = city.State
+	case t.City != nil:
+		city = t.City
+		state = city.State
+	case t.State != nil:
+		state = t.State
+	default:
+		return nil
+	}
+
+	breadcrumbs := []Breadcrumb{{Name: "Start", Path: "/"}}
+	// skip the state of city states, e.g. "Berlin > Berlin"
+	if state != nil && (city == nil || city.Name != state.Name) {
+		breadcrumbs = append(breadcrumbs, Breadcrumb{Name: state.Name, Path: state.Slug()})
+	}
+	if city != nil {
+		breadcrumbs = append(breadcrumbs, Breadcrumb{Name: city.Name, Path: city.Slug()})
+	}
+	if t.Club != nil {
+		breadcrumbs = append(breadcrumbs, Breadcrumb{Name: t.Club.Name, Path: t.Club.Slug()})
+	} else if t.CityTag != nil {
+		breadcrumbs = append(breadcrumbs, Breadcrumb{Name: t.CityTag.Tag.Name, Path: t.CityTag.Slug()})
+	} else if t.Weekday != nil {
+		breadcrumbs = append(breadcrumbs, Breadcrumb{Name: t.Weekday.Name(), Path: t.Weekday.Slug()})
+	}
+	breadcrumbs[len(breadcrumbs)-1].Current = true
+	return breadcrumbs
+}
+
+type jsonLD map[string]any
+
+func administrativeArea(state *State) jsonLD {
+	return jsonLD{
+		"@type": "AdministrativeArea",
+		"name":  state.Name,
+		"url":   createCanonicalURL(state.Slug()),
+		"containedInPlace": jsonLD{
+			"@type": "Country",
+			"name":  "Deutschland",
+		},
+	}
+}
+
+func cityPlace(city *City) jsonLD {
+	place := jsonLD{
+		"@type": "City",
+		"name":  city.Name,
+		"url":   createCanonicalURL(city.Slug()),
+	}
+	if city.LatLon != nil {
+		place["geo"] = jsonLD{"@type": "GeoCoordinates", "latitude": city.LatLon.Lat, "longitude": city.LatLon.Lon}
+	}
+	var region jsonLD
+	if city.State != nil {
+		region = administrativeArea(city.State)
+	}
+	if city.District != "" {
+		district := jsonLD{"@type": "AdministrativeArea", "name": city.District}
+		if region != nil {
+			district["containedInPlace"] = region
+		}
+		region = district
+	}
+	if region != nil {
+		place["containedInPlace"] = region
+	}
+	return place
+}
+
+func eventJSONLD(event *Event) jsonLD {
+	e := jsonLD{
+		"@type":               "SportsEvent",
+		"name":                event.Name,
+		"url":                 eventLink(event),
+		"startDate":           event.DateISO(),
+		"eventAttendanceMode": "https://schema.org/OfflineEventAttendanceMode",
+	}
+	if event.Description != "" {
+		e["description"] = event.Description
+	}
+	if event.City != nil {
+		location := cityPlace(event.City)
+		if event.Location != "" {
+			location = jsonLD{"@type": "Place", "name": event.Location, "containedInPlace": location}
+		}
+		e["location"] = location
+	}
+	if event.Club != nil {
+		e["organizer"] = jsonLD{"@type": "SportsOrganization", "name": event.Club.Name, "url": createCanonicalURL(event.Club.Slug())}
+	}
+	return e
+}
+
+// JSONLD returns the structured data (schema.org) of the page, or "" if there is none.
+func (t TemplateData) JSONLD() template.JS {
+	graph := make([]jsonLD, 0)
+
+	if breadcrumbs := t.Breadcrumbs(); len(breadcrumbs) > 0 {
+		items := make([]jsonLD, 0, len(breadcrumbs))
+		for i, breadcrumb := range breadcrumbs {
+			items = append(items, jsonLD{
+				"@type":    "ListItem",
+				"position": i + 1,
+				"name":     breadcrumb.Name,
+				"item":     createCanonicalURL(breadcrumb.Path),
+			})
+		}
+		graph = append(graph, jsonLD{"@type": "BreadcrumbList", "itemListElement": items})
+	}
+
+	var events []*Event
+	switch {
+	case t.Club != nil:
+		events = t.Club.Events
+		address := jsonLD{
+			"@type":           "PostalAddress",
+			"addressLocality": t.Club.City.Name,
+			"addressCountry":  "DE",
+		}
+		if t.Club.City.State != nil {
+			address["addressRegion"] = t.Club.City.State.Name
+		}
+		club := jsonLD{
+			"@type":    "SportsOrganization",
+			"name":     t.Club.Name,
+			"url":      createCanonicalURL(t.Club.Slug()),
+			"sport":    "Running",
+			"address":  address,
+			"location": cityPlace(t.Club.City),
+		}
+		if cities := t.Club.LocationCities(); len(cities) > 0 {
+			places := []jsonLD{cityPlace(t.Club.City)}
+			for _, city := range cities {
+				places = append(places, cityPlace(city))
+			}
+			club["location"] = places
+		}
+		graph = append(graph, club)
+	case t.City != nil:
+		if t.CityTag == nil && t.Weekday == nil {
+			// only the city page itself lists the events
+			events = t.City.Events
+		}
+		graph = append(graph, cityPlace(t.City))
+	case t.State != nil:
+		graph = append(graph, administrativeArea(t.State))
+	case t.Post != nil && !t.Post.Draft:
+		article := jsonLD{
+			"@type":    "BlogPosting",
+			"headline": t.Post.Title,
+			"url":      createCanonicalURL(t.Post.Slug),
+		}
+		if !t.Post.Published.IsZero() {
+			article["datePublished"] = t.Post.Published.Format("2006-01-02")
+		}
+		if t.Post.Description != "" {
+			article["description"] = t.Post.Description
+		}
+		if t.Post.UpdatedLabel() != "" {
+			article["dateModified"] = t.Post.Updated.Format("2006-01-02")
+		}
+		if t.Post.Author != "" {
+			article["author"] = jsonLD{"@type": "Person", "name": t.Post.Author}
+		}
+		if t.Post.Cover != "" {
+			article["image"] = createCanonicalURL(t.Post.Cover)
+		}
+		graph = append(graph, article)
+	}
+
+	for _, event := range events {
+		graph = append(graph, eventJSONLD(event))
+	}
+
+	if len(graph) == 0 {
+		return ""
+	}
+	buf, err := json.Marshal(jsonLD{"@context": "https://schema.org", "@graph": graph})
+	if err != nil {
+		return ""
+	}
+	return template.JS(buf)
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+const eventsCalendarFile = "termine.ics"
+
+// EventsCalendar is the path of the iCal file with the city's upcoming events.
+func (c *City) EventsCalendar() string {
+	return c.Slug() + "/" + eventsCalendarFile
+}
+
+// EventsCalendar is the path of the iCal file with the club's upcoming events.
+func (c *Club) EventsCalendar() string {
+	return c.Slug() + "/" + eventsCalendarFile
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icalLine folds a content line after 75 octets (RFC 5545, 3.1), without
+// splitting UTF-8 sequences.
+func icalLine(buf *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// eventLocation joins the event's location and city, e.g. "Stadtpark, Hamburg".
+func eventLocation(event *Event) string {
+	parts := make([]string, 0, 2)
+	if event.Location != "" {
+		parts = append(parts, event.Location)
+	}
+	if event.City != nil && !strings.Contains(event.Location, event.City.Name) {
+		parts = append(parts, event.City.Name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// eventLink is the event's own URL or its anchor on the events page.
+func eventLink(event *Event) string {
+	if event.URL != "" {
+		return event.URL
+	}
+	return createCanonicalURL(event.Slug())
+}
+
+// buildICal creates an iCalendar (RFC 5545) file with the events; now is
+// used as DTSTAMP.
+func buildICal(name string, events []*Event, now time.Time) []byte {
+	const utcFormat = "20060102T150405Z"
+	var buf strings.Builder
+	icalLine(&buf, "BEGIN:VCALENDAR")
+	icalLine(&buf, "VERSION:2.0")
+	icalLine(&buf, "PRODID:-//socialrunclubs.de//Termine//DE")
+	icalLine(&buf, "CALSCALE:GREGORIAN")
+	icalLine(&buf, "X-WR-CALNAME:"+icalEscaper.Replace(name))
+	for _, event := range events {
+		icalLine(&buf, "BEGIN:VEVENT")
+		icalLine(&buf, fmt.Sprintf("UID:%s@socialrunclubs.de", event.ID()))
+		icalLine(&buf, "DTSTAMP:"+now.UTC().Format(utcFormat))
+		if event.HasTime {
+			icalLine(&buf, "DTSTART:"+event.Start.UTC().Format(utcFormat))
+			icalLine(&buf, "DTEND:"+event.End().UTC().Format(utcFormat))
+		} else {
+			icalLine(&buf, "DTSTART;VALUE=DATE:"+event.Start.Format("20060102"))
+			icalLine(&buf, "DTEND;VALUE=DATE:"+event.End().Format("20060102"))
+		}
+		summary := event.Name
+		if event.Club != nil {
+			summary = fmt.Sprintf("%s (%s)", event.Name, event.Club.Name)
+		}
+		icalLine(&buf, "SUMMARY:"+icalEscaper.Replace(summary))
+		if event.Description != "" {
+			icalLine(&buf, "DESCRIPTION:"+icalEscaper.Replace(event.Description))
+		}
+		if location := eventLocation(event); location != "" {
+			icalLine(&buf, "LOCATION:"+icalEscaper.Replace(location))
+		}
+		icalLine(&buf, "URL:"+eventLink(event))
+		icalLine(&buf, "END:VEVENT")
+	}
+	icalLine(&buf, "END:VCALENDAR")
+	return []byte(buf.String())
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Link    atomLink `xml:"link"`
+	Updated string   `xml:"updated"`
+	Summary string   `xml:"summary"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// buildAtomFeed creates an Atom feed of the upcoming events; now is used as
+// update time.
+func buildAtomFeed(events []*Event, now time.Time) ([]byte, error) {
+	updated := now.UTC().Format(time.RFC3339)
+	feed := atomFeed{
+		Title:   "Termine - Social Run Clubs",
+		ID:      createCanonicalURL("/termine.html"),
+		Links:   []atomLink{{Href: createCanonicalURL("/termine.html")}, {Href: createCanonicalURL("/termine.atom"), Rel: "self"}},
+		Updated: updated,
+		Author:  "socialrunclubs.de",
+	}
+	for _, event := range events {
+		summary := event.DateLabel()
+		if location := eventLocation(event); location != "" {
+			summary += ", " + location
+		}
+		if event.Club != nil {
+			summary += ", " + event.Club.Name
+		}
+		if event.Description != "" {
+			summary += ": " + event.Description
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   event.Name,
+			ID:      createCanonicalURL(event.Slug()),
+			Link:    atomLink{Href: eventLink(event)},
+			Updated: updated,
+			Summary: summary,
+		})
+	}
+	buf, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(buf, '\n')...), nil
+}
+
+// renderEventFeeds writes the site-wide iCal file and Atom feed of the
+// upcoming events, and iCal files for cities and clubs with events.
+func renderEventFeeds(data *Data, config Config) error {
+	type calendar struct {
+		name   string
+		path   string
+		events []*Event
+	}
+	calendars := []calendar{
+		{"Social Run Clubs - Termine", "/" + eventsCalendarFile, data.Events},
+	}
+	for _, city := range data.Cities {
+		if len(city.Events) > 0 {
+			calendars = append(calendars, calendar{fmt.Sprintf("Social Run Clubs in %s - Termine", city.Name), city.EventsCalendar(), city.Events})
+		}
+		for _, club := range city.Clubs {
+			if len(club.Events) > 0 {
+				calendars = append(calendars, calendar{fmt.Sprintf("%s - Termine", club.Name), club.EventsCalendar(), club.Events})
+			}
+		}
+	}
+
+	for _, cal := range calendars {
+		fileName := filepath.Join(config.OutputDir, cal.path)
+		if err := utils.MakeDir(filepath.Dir(fileName)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fileName, buildICal(cal.name, cal.events, data.Now), 0644); err != nil {
+			return fmt.Errorf("writing calendar %q: %w", fileName, err)
+		}
+	}
+
+	feed, err := buildAtomFeed(data.Events, data.Now)
+	if err != nil {
+		return fmt.Errorf("encoding events feed: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(config.OutputDir, "termine.atom"), feed, 0644); err != nil {
+		return fmt.Errorf("writing events feed: %w", err)
+	}
+	return nil
+}
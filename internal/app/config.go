@@ -24,6 +24,44 @@ type Config struct {
 	Umami struct {
 		WebsiteId string
 	}
+	Images struct {
+		WebP    string   // optional WebP encoder command, e.g. "cwebp -quiet -q 80 IN -o OUT"
+		AVIF    string   // optional AVIF encoder command, e.g. "avifenc -q 60 IN OUT"
+		Sources []string // image provider order, e.g. ["manual", "instagram", "strava", "directory", "website"]
+	}
+	StaticMaps struct {
+		TileURL     string // tile server for the static map images, e.g. "https://tile.openstreetmap.org/{z}/{x}/{y}.png"
+		TileDir     string // local tiles ({z}/{x}/{y}.png), used instead of TileURL, e.g. for tests; both empty: no static maps
+		UserAgent   string // sent to the tile server, default: "socialrunclubs.de"
+		Attribution string // shown with the images, default: "© OpenStreetMap-Mitwirkende"
+		Width       int    // default: 800
+		Height      int    // default: 400
+		ClubZoom    int    // default: 13
+		CityZoom    int    // the city maps show all of Germany, default: 5
+	}
+	NearbyClubs struct {
+		RadiusKM float64 // list clubs of other cities within this distance on city pages, default: 25
+		MaxClubs int     // default: 10
+	}
+	CityTags struct {
+		MinClubs int // create a city page of a tag (e.g. /hamburg/tag/anfaenger/) if it has at least this number of clubs, default: 3
+	}
+	Coverage struct {
+		MinPopulation int     // towns with at least this population are checked, default: 50000
+		RadiusKM      float64 // towns without club within this distance are gaps, default: 15
+	}
+	Plausibility struct {
+		Boundary      string  // GeoJSON file with the outline of Germany (feature property "name"), default: "data/germany.geojson" if it exists
+		MaxDistanceKM float64 // max. distance of a club's meeting point from its city, default: 50
+	}
+	Neighbourhoods map[string]string // city name => GeoJSON file with the city's neighbourhoods (feature property "name")
+	Geocoding      struct {
+		Providers        []string // geocoder order, default: ["gazetteer", "nominatim"]
+		ReverseProviders []string // geocoder order for the regions of cities, default: ["nominatim", "gazetteer"]
+		Nominatim        string   // Nominatim endpoint, default: utils.NominatimURL
+		Gazetteer        string   // municipality list, default: "data/gemeinden.csv"
+		MaxAge           string   // refresh cached results older than this, e.g. "2160h"; default: never
+	}
 }
 
 // loadConfig loads configuration from a JSON file into the given config struct.
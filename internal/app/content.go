@@ -0,0 +1,297 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// maxSummaryItems limits the enumerations (tags, cities, ...) of a summary.
+const maxSummaryItems = 3
+
+var weekdayNames = [...]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"}
+
+var reWeekday = regexp.MustCompile(`(?i)\b(sonntag|montag|dienstag|mittwoch|donnerstag|freitag|samstag)s?\b`)
+
+// Weekdays returns the weekdays of the club's runs at all locations, Monday
+// first. Without runs in the sheets, the weekdays mentioned in the description
+// are used (e.g. "jeden Sonntag", "donnerstags").
+func (c *Club) Weekdays() []time.Weekday {
+	found := make(map[time.Weekday]bool)
+	for _, run := range c.Runs {
+		found[run.Weekday] = true
+	}
+	for _, location := range c.Locations {
+		for _, run := range location.Runs {
+			found[run.Weekday] = true
+		}
+	}
+	if len(found) > 0 {
+		return sortedWeekdays(found)
+	}
+	for _, match := range reWeekday.FindAllStringSubmatch(c.DescriptionRaw, -1) {
+		for day, name := range weekdayNames {
+			if strings.EqualFold(match[1], name) {
+				found[time.Weekday(day)] = true
+			}
+		}
+	}
+	return sortedWeekdays(found)
+}
+
+// sortedWeekdays returns the weekdays of the set, Monday first.
+func sortedWeekdays(set map[time.Weekday]bool) []time.Weekday {
+	result := make([]time.Weekday, 0, len(set))
+	for i := 1; i <= 7; i++ {
+		if day := time.Weekday(i % 7); set[day] {
+			result = append(result, day)
+		}
+	}
+	return result
+}
+
+// weekdayAdverb returns the German adverb of the weekday, e.g. "sonntags".
+func weekdayAdverb(day time.Weekday) string {
+	return strings.ToLower(weekdayNames[day]) + "s"
+}
+
+// joinGerman joins the items as German enumeration, e.g. "A, B und C".
+func joinGerman(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " und " + items[len(items)-1]
+}
+
+func pluralClubs(n int) string {
+	if n == 1 {
+		return "einen Social Run Club"
+	}
+	return fmt.Sprintf("%d Social Run Clubs", n)
+}
+
+// countedName is a name with a count, e.g. a tag and the number of its clubs.
+type countedName struct {
+	Name  string
+	Count int
+}
+
+// topNames returns the names with the highest counts (ties by name).
+func topNames(counts map[string]int, maxItems int) []countedName {
+	result := make([]countedName, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, countedName{name, count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > maxItems {
+		result = result[:maxItems]
+	}
+	return result
+}
+
+// newestClub returns the most recently added club, or nil if no club has a date.
+func newestClub(clubs []*Club) *Club {
+	var newest *Club
+	for _, club := range clubs {
+		if club.AddedRaw != "" && (newest == nil || club.AddedRaw > newest.AddedRaw) {
+			newest = club
+		}
+	}
+	return newest
+}
+
+func addedLabel(club *Club) string {
+	if added, err := time.Parse("2006-01-02", club.AddedRaw); err == nil {
+		return utils.FormatDate(added)
+	}
+	return club.AddedRaw
+}
+
+func clubsWeekdaysSentence(clubs []*Club) string {
+	weekdays := make(map[time.Weekday]bool)
+	for _, club := range clubs {
+		for _, day := range club.Weekdays() {
+			weekdays[day] = true
+		}
+	}
+	if len(weekdays) == 0 {
+		return ""
+	}
+	adverbs := make([]string, 0, len(weekdays))
+	for _, day := range sortedWeekdays(weekdays) {
+		adverbs = append(adverbs, weekdayAdverb(day))
+	}
+	if len(adverbs) == 7 {
+		return "Gelaufen wird an jedem Tag der Woche."
+	}
+	return fmt.Sprintf("Gelaufen wird unter anderem %s.", joinGerman(adverbs))
+}
+
+func newestClubSentence(clubs []*Club) string {
+	newest := newestClub(clubs)
+	if newest == nil {
+		return ""
+	}
+	return fmt.Sprintf("Zuletzt neu hinzugekommen ist %s (am %s).", newest.Name, addedLabel(newest))
+}
+
+// citySummary builds a short description of the city from the data, so
+// city pages do not only consist of boilerplate text.
+func citySummary(city *City, nearestClub *utils.Neighbour[*Club]) string {
+	sentences := make([]string, 0)
+	clubs := city.AllClubs()
+
+	if len(clubs) == 0 {
+		if city.Population > 0 {
+			sentences = append(sentences, fmt.Sprintf("In %s mit seinen %s Einwohner:innen ist bisher noch kein Social Run Club eingetragen.", city.Name, city.PopulationLabel()))
+		} else {
+			sentences = append(sentences, fmt.Sprintf("In %s ist bisher noch kein Social Run Club eingetragen.", city.Name))
+		}
+		if nearestClub != nil {
+			sentences = append(sentences, fmt.Sprintf("Der nächste Club ist %s in %s, etwa %s entfernt.", nearestClub.Item.Name, nearestClub.Item.City.Name, utils.FormatDistance(nearestClub.Distance)))
+		}
+	} else {
+		sentence := fmt.Sprintf("In %s gibt es %s", city.Name, pluralClubs(len(clubs)))
+		if neighbourhoods := city.Neighbourhoods(); len(neighbourhoods) > 1 {
+			names := make([]string, 0, len(neighbourhoods))
+			for _, n := range neighbourhoods {
+				if n.Name != "" {
+					names = append(names, n.Name)
+				}
+			}
+			if len(names) > maxSummaryItems {
+				sentence += fmt.Sprintf(", verteilt auf %d Stadtteile wie %s", len(names), joinGerman(names[:maxSummaryItems]))
+			} else {
+				sentence += fmt.Sprintf(" in den Stadtteilen %s", joinGerman(names))
+			}
+		}
+		sentences = append(sentences, sentence+".")
+
+		tagCounts := make(map[string]int)
+		for _, club := range clubs {
+			for _, tag := range club.Tags {
+				tagCounts[tag.Name]++
+			}
+		}
+		if tags := topNames(tagCounts, maxSummaryItems); len(tags) > 0 {
+			names := make([]string, 0, len(tags))
+			for _, tag := range tags {
+				names = append(names, tag.Name)
+			}
+			sentences = append(sentences, fmt.Sprintf("Typisch für die Clubs hier: %s.", joinGerman(names)))
+		}
+		if sentence := clubsWeekdaysSentence(clubs); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		if sentence := newestClubSentence(clubs); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+
+	if len(city.NearestCities) > 0 && city.LatLon != nil {
+		names := make([]string, 0, len(city.NearestCities))
+		for _, other := range city.NearestCities {
+			if other.LatLon == nil {
+				continue
+			}
+			names = append(names, fmt.Sprintf("%s (%s)", other.Name, utils.FormatDistance(utils.Distance(*city.LatLon, *other.LatLon))))
+		}
+		if len(names) > 0 {
+			prefix := "Weitere Run Clubs in der Umgebung gibt es in"
+			if len(clubs) == 0 {
+				prefix = "Die nächsten Städte mit Run Clubs sind"
+			}
+			sentences = append(sentences, fmt.Sprintf("%s %s.", prefix, joinGerman(names)))
+		}
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// tagSummary builds a short description of the tag's clubs from the data.
+func tagSummary(tag *Tag) string {
+	if len(tag.Clubs) == 0 {
+		return ""
+	}
+	sentences := make([]string, 0)
+
+	cityCounts := make(map[string]int)
+	stateCounts := make(map[string]int)
+	otherTags := make(map[string]int)
+	for _, club := range tag.Clubs {
+		cityCounts[club.City.Name]++
+		if club.City.State != nil {
+			stateCounts[club.City.State.Name]++
+		}
+		for _, other := range club.Tags {
+			if other != tag {
+				otherTags[other.Name]++
+			}
+		}
+	}
+
+	var sentence string
+	if len(tag.Clubs) == 1 {
+		sentence = fmt.Sprintf("Der Kategorie %s ist ein Social Run Club in %s zugeordnet", tag.Name, tag.Clubs[0].City.Name)
+	} else if len(cityCounts) == 1 {
+		sentence = fmt.Sprintf("Der Kategorie %s sind %d Social Run Clubs in %s zugeordnet", tag.Name, len(tag.Clubs), tag.Clubs[0].City.Name)
+	} else {
+		cities := topNames(cityCounts, maxSummaryItems)
+		names := make([]string, 0, len(cities))
+		for _, city := range cities {
+			names = append(names, fmt.Sprintf("%s (%d)", city.Name, city.Count))
+		}
+		sentence = fmt.Sprintf("Der Kategorie %s sind %d Social Run Clubs in %d Städten zugeordnet, die meisten in %s", tag.Name, len(tag.Clubs), len(cityCounts), joinGerman(names))
+	}
+	sentences = append(sentences, sentence+".")
+
+	if len(stateCounts) > 1 {
+		sentences = append(sentences, fmt.Sprintf("Vertreten sind %d Bundesländer.", len(stateCounts)))
+	}
+	if tags := topNames(otherTags, maxSummaryItems); len(tags) > 0 {
+		names := make([]string, 0, len(tags))
+		for _, other := range tags {
+			names = append(names, other.Name)
+		}
+		sentences = append(sentences, fmt.Sprintf("Häufig kombiniert mit: %s.", joinGerman(names)))
+	}
+	if sentence := clubsWeekdaysSentence(tag.Clubs); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	if sentence := newestClubSentence(tag.Clubs); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// GenerateContent creates the data-driven summaries of the city and tag pages;
+// it needs the nearest cities (see AnnotateNearestCities).
+func GenerateContent(data *Data) error {
+	index := newClubIndex(data.Clubs)
+	for _, city := range data.Cities {
+		var nearestClub *utils.Neighbour[*Club]
+		if !city.HasClubs() && city.LatLon != nil {
+			if nearest := uniqueClubs(index.Nearest(*city.LatLon, 1, nil)); len(nearest) > 0 {
+				nearestClub = &nearest[0]
+			}
+		}
+		city.Summary = citySummary(city, nearestClub)
+	}
+	for _, tag := range data.Tags {
+		tag.Summary = tagSummary(tag)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestClubWeekdays(t *testing.T) {
+	club := &Club{DescriptionRaw: "Wir laufen sonntags um 10 Uhr und jeden Dienstag. Montagmorgen ist frei."}
+	days := club.Weekdays()
+	if len(days) != 2 || days[0] != time.Tuesday || days[1] != time.Sunday {
+		t.Errorf("Weekdays() = %v", days)
+	}
+	if days := (&Club{DescriptionRaw: "Treffpunkt am Park"}).Weekdays(); len(days) != 0 {
+		t.Errorf("Weekdays() without weekday = %v", days)
+	}
+}
+
+func TestJoinGerman(t *testing.T) {
+	for _, tc := range []struct {
+		items    []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"a"}, "a"},
+		{[]string{"a", "b"}, "a und b"},
+		{[]string{"a", "b", "c"}, "a, b und c"},
+	} {
+		if actual := joinGerman(tc.items); actual != tc.expected {
+			t.Errorf("joinGerman(%v) = %q, want %q", tc.items, actual, tc.expected)
+		}
+	}
+}
+
+func TestGenerateContent(t *testing.T) {
+	berlin := &City{Name: "Berlin", LatLon: &utils.LatLon{Lat: 52.52, Lon: 13.405}}
+	potsdam := &City{Name: "Potsdam", LatLon: &utils.LatLon{Lat: 52.39, Lon: 13.06}}
+	leer := &City{Name: "Leer", LatLon: &utils.LatLon{Lat: 53.23, Lon: 7.45}, Population: 34000}
+	data := &Data{Cities: []*City{berlin, potsdam, leer}}
+	trail := data.getOrAddTag("trail")
+	kaffee := data.getOrAddTag("kaffee")
+
+	a := &Club{Name: "A", City: berlin, Tags: []*Tag{trail, kaffee}, DescriptionRaw: "Sonntags", AddedRaw: "2025-01-02"}
+	b := &Club{Name: "B", City: berlin, Tags: []*Tag{trail}, DescriptionRaw: "jeden Mittwoch", AddedRaw: "2025-03-03"}
+	c := &Club{Name: "C", City: potsdam, Tags: []*Tag{trail}}
+	data.Clubs = []*Club{a, b, c}
+	berlin.Clubs = []*Club{a, b}
+	potsdam.Clubs = []*Club{c}
+	trail.Clubs = []*Club{a, b, c}
+	kaffee.Clubs = []*Club{a}
+	if err := AnnotateNearestCities(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateContent(data); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	for _, tc := range []struct {
+		summary  string
+		expected []string
+	}{
+		{berlin.Summary, []string{"In Berlin gibt es 2 Social Run Clubs.", "Typisch für die Clubs hier: trail und kaffee.", "Gelaufen wird unter anderem mittwochs und sonntags.", "Zuletzt neu hinzugekommen ist B (am 3. März 2025).", "Potsdam (27 km)"}},
+		{potsdam.Summary, []string{"In Potsdam gibt es einen Social Run Club."}},
+		{leer.Summary, []string{"In Leer mit seinen 34.000 Einwohner:innen ist bisher noch kein Social Run Club eingetragen.", "Der nächste Club ist C in Potsdam", "Die nächsten Städte mit Run Clubs sind Potsdam"}},
+		{trail.Summary, []string{"Der Kategorie trail sind 3 Social Run Clubs in 2 Städten zugeordnet, die meisten in Berlin (2) und Potsdam (1).", "Häufig kombiniert mit: kaffee."}},
+		{kaffee.Summary, []string{"Der Kategorie kaffee ist ein Social Run Club in Berlin zugeordnet."}},
+	} {
+		for _, expected := range tc.expected {
+			if !strings.Contains(tc.summary, expected) {
+				t.Errorf("summary %q does not contain %q", tc.summary, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+const (
+	defaultCoverageMinPopulation = 50000
+	defaultCoverageRadiusKM      = 15
+)
+
+// CoverageGap is a town without any run club nearby.
+type CoverageGap struct {
+	Name                string
+	State               string
+	LatLon              utils.LatLon
+	Population          int
+	City                *City   // nil if the town is not part of the CITIES sheet
+	NearestClub         *Club   // nil if there are no clubs at all
+	NearestClubDistance float64 // km
+}
+
+func (g CoverageGap) PopulationLabel() string {
+	return utils.FormatNumber(g.Population)
+}
+
+func (g CoverageGap) NearestClubLabel() string {
+	if g.NearestClub == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s)", g.NearestClub.Name, utils.FormatDistance(g.NearestClubDistance))
+}
+
+// StateCoverage relates the number of clubs of a state to its population.
+type StateCoverage struct {
+	Name       string
+	Population int
+	Clubs      int
+}
+
+func (s StateCoverage) ClubsPerMillion() float64 {
+	if s.Population == 0 {
+		return 0
+	}
+	return float64(s.Clubs) * 1000000 / float64(s.Population)
+}
+
+func (s StateCoverage) PopulationLabel() string {
+	if s.Population == 0 {
+		return "?"
+	}
+	return utils.FormatNumber(s.Population)
+}
+
+func (s StateCoverage) ClubsPerMillionLabel() string {
+	if s.Population == 0 {
+		return "?"
+	}
+	return strings.Replace(fmt.Sprintf("%.1f", s.ClubsPerMillion()), ".", ",", 1)
+}
+
+// CoverageReport lists the under-served areas, used for outreach and content planning.
+type CoverageReport struct {
+	MinPopulation int
+	RadiusKM      float64
+	Gaps          []CoverageGap   // sorted by decreasing population
+	States        []StateCoverage // sorted by increasing clubs per capita, states with unknown population last
+}
+
+func (r *CoverageReport) MinPopulationLabel() string {
+	return utils.FormatNumber(r.MinPopulation)
+}
+
+type coveragePlace struct {
+	name       string
+	state      string
+	latLon     utils.LatLon
+	population int
+	city       *City
+}
+
+// statePopulations sums the populations of the gazetteer's municipalities by
+// state; the sums are only complete for the full municipality list, see
+// cmd/import_gemeinden.
+func statePopulations(gazetteer utils.Gazetteer) map[string]int {
+	populations := make(map[string]int)
+	for _, entries := range gazetteer {
+		for _, entry := range entries {
+			state := StateByAGS(entry.AGS)
+			if state == "" {
+				state = entry.State
+			}
+			if state != "" {
+				populations[state] += entry.Population
+			}
+		}
+	}
+	return populations
+}
+
+// coveragePlaces combines the cities of the CITIES sheet with the remaining
+// municipalities of the gazetteer.
+func coveragePlaces(data *Data, gazetteer utils.Gazetteer) []coveragePlace {
+	places := make([]coveragePlace, 0)
+	citiesByName := make(map[string]*City)
+	citiesByAGS := make(map[string]*City)
+	for _, city := range data.Cities {
+		citiesByName[strings.ToLower(city.Name)] = city
+		if city.AGS != "" {
+			citiesByAGS[city.AGS] = city
+		}
+		if city.LatLon == nil {
+			continue
+		}
+		place := coveragePlace{name: city.Name, latLon: *city.LatLon, population: city.Population, city: city}
+		if city.State != nil {
+			place.state = city.State.Name
+		}
+		places = append(places, place)
+	}
+
+	for _, entries := range gazetteer {
+		for _, entry := range entries {
+			if _, found := citiesByName[strings.ToLower(entry.Name)]; found {
+				continue
+			}
+			if _, found := citiesByAGS[entry.AGS]; found && entry.AGS != "" {
+				continue
+			}
+			places = append(places, coveragePlace{name: entry.Name, state: entry.State, latLon: entry.LatLon, population: entry.Population})
+		}
+	}
+	return places
+}
+
+// AnalyzeCoverage determines the towns above the configured population without
+// a club within the configured radius, and the clubs per capita of all states.
+func AnalyzeCoverage(data *Data, gazetteer utils.Gazetteer, config Config) *CoverageReport {
+	report := &CoverageReport{
+		MinPopulation: config.Coverage.MinPopulation,
+		RadiusKM:      config.Coverage.RadiusKM,
+	}
+	if report.MinPopulation <= 0 {
+		report.MinPopulation = defaultCoverageMinPopulation
+	}
+	if report.RadiusKM <= 0 {
+		report.RadiusKM = defaultCoverageRadiusKM
+	}
+
+	index := newClubIndex(data.Clubs)
+	for _, place := range coveragePlaces(data, gazetteer) {
+		if place.population < report.MinPopulation {
+			continue
+		}
+		gap := CoverageGap{Name: place.name, State: place.state, LatLon: place.latLon, Population: place.population, City: place.city}
+		if nearest := uniqueClubs(index.Nearest(place.latLon, 1, nil)); len(nearest) > 0 {
+			if nearest[0].Distance <= report.RadiusKM {
+				continue
+			}
+			gap.NearestClub = nearest[0].Item
+			gap.NearestClubDistance = nearest[0].Distance
+		}
+		report.Gaps = append(report.Gaps, gap)
+	}
+	sort.Slice(report.Gaps, func(i, j int) bool {
+		if report.Gaps[i].Population != report.Gaps[j].Population {
+			return report.Gaps[i].Population > report.Gaps[j].Population
+		}
+		return report.Gaps[i].Name < report.Gaps[j].Name
+	})
+
+	populations := statePopulations(gazetteer)
+	for _, name := range StateNames {
+		coverage := StateCoverage{Name: name, Population: populations[name]}
+		if state, found := data.StateMap[name]; found {
+			coverage.Clubs = state.NumberOfClubs()
+		}
+		report.States = append(report.States, coverage)
+	}
+	sort.SliceStable(report.States, func(i, j int) bool {
+		if (report.States[i].Population == 0) != (report.States[j].Population == 0) {
+			return report.States[j].Population == 0
+		}
+		return report.States[i].ClubsPerMillion() < report.States[j].ClubsPerMillion()
+	})
+
+	return report
+}
+
+// GeoJSON creates a GeoJSON layer of the coverage gaps.
+func (r *CoverageReport) GeoJSON() ([]byte, error) {
+	points := make([]utils.Point, 0, len(r.Gaps))
+	for _, gap := range r.Gaps {
+		properties := map[string]any{
+			"name":       gap.Name,
+			"state":      gap.State,
+			"population": gap.Population,
+		}
+		if gap.City != nil {
+			properties["url"] = createCanonicalURL(gap.City.Slug())
+		}
+		if gap.NearestClub != nil {
+			properties["nearest_club"] = gap.NearestClub.Name
+			properties["nearest_club_km"] = math.Round(gap.NearestClubDistance*10) / 10
+		}
+		points = append(points, utils.Point{LatLon: gap.LatLon, Properties: properties})
+	}
+	return utils.PointsToGeoJSON(points)
+}
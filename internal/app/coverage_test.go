@@ -0,0 +1,98 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestAnalyzeCoverage(t *testing.T) {
+	berlin := &City{Name: "Berlin", LatLon: &utils.LatLon{Lat: 52.52, Lon: 13.405}}
+	potsdam := &City{Name: "Potsdam", LatLon: &utils.LatLon{Lat: 52.39, Lon: 13.065}, Population: 187000}
+	gotha := &City{Name: "Gotha", LatLon: &utils.LatLon{Lat: 50.95, Lon: 10.70}, Population: 46000}
+	cottbus := &City{Name: "Cottbus", LatLon: &utils.LatLon{Lat: 51.76, Lon: 14.33}, Population: 99000}
+	club := &Club{Name: "Berlin Club", City: berlin}
+	berlin.Clubs = []*Club{club}
+
+	data := &Data{Cities: []*City{berlin, potsdam, gotha, cottbus}, Clubs: []*Club{club}}
+	data.setCityState(berlin, "Berlin")
+	data.setCityState(potsdam, "Brandenburg")
+	data.setCityState(cottbus, "Brandenburg")
+
+	gazetteer := utils.Gazetteer{
+		"potsdam": {{Name: "Potsdam", LatLon: utils.LatLon{Lat: 52.40, Lon: 13.06}, Region: utils.Region{State: "Brandenburg"}, Population: 187000}},
+		"erfurt":  {{Name: "Erfurt", LatLon: utils.LatLon{Lat: 50.98, Lon: 11.03}, Region: utils.Region{State: "Thüringen"}, Population: 216000}},
+		"bernau":  {{Name: "Bernau", LatLon: utils.LatLon{Lat: 52.68, Lon: 13.59}, Population: 40000}},
+		"berlin":  {{Name: "Berlin", AGS: "11000000", LatLon: utils.LatLon{Lat: 52.52, Lon: 13.405}, Population: 3755000}},
+	}
+
+	report := AnalyzeCoverage(data, gazetteer, Config{})
+	if report.MinPopulation != 50000 || report.RadiusKM != 15 {
+		t.Errorf("defaults = %d, %f", report.MinPopulation, report.RadiusKM)
+	}
+
+	// Gotha and Bernau are too small, Berlin has a club
+	expected := []struct {
+		name  string
+		state string
+		city  *City
+	}{
+		{"Erfurt", "Thüringen", nil},
+		{"Potsdam", "Brandenburg", potsdam},
+		{"Cottbus", "Brandenburg", cottbus},
+	}
+	if len(report.Gaps) != len(expected) {
+		t.Fatalf("Gaps = %+v", report.Gaps)
+	}
+	for i, e := range expected {
+		gap := report.Gaps[i]
+		if gap.Name != e.name || gap.State != e.state || gap.City != e.city || gap.NearestClub != club {
+			t.Errorf("gap %d = %+v, want %s", i, gap, e.name)
+		}
+	}
+
+	if len(report.States) != len(StateNames) {
+		t.Fatalf("States = %+v", report.States)
+	}
+	// state populations are the sums of the gazetteer's municipalities;
+	// states without population data come last
+	if brandenburg := report.States[0]; brandenburg.Name != "Brandenburg" || brandenburg.Population != 187000 {
+		t.Errorf("worst covered state = %+v, want Brandenburg", brandenburg)
+	}
+	if berlin := report.States[2]; berlin.Name != "Berlin" || berlin.Clubs != 1 || berlin.Population != 3755000 {
+		t.Errorf("best covered state = %+v, want Berlin", berlin)
+	}
+	if last := report.States[len(report.States)-1]; last.Population != 0 || last.PopulationLabel() != "?" || last.ClubsPerMillionLabel() != "?" {
+		t.Errorf("state without population = %+v", last)
+	}
+
+	// with a larger radius, Potsdam is covered by the Berlin club
+	var config Config
+	config.Coverage.RadiusKM = 30
+	config.Coverage.MinPopulation = 40000
+	report = AnalyzeCoverage(data, gazetteer, config)
+	names := make([]string, 0)
+	for _, gap := range report.Gaps {
+		names = append(names, gap.Name)
+	}
+	if len(names) != 3 || names[0] != "Erfurt" || names[1] != "Cottbus" || names[2] != "Gotha" {
+		t.Errorf("Gaps = %v", names)
+	}
+
+	buf, err := report.GeoJSON()
+	if err != nil {
+		t.Fatalf("GeoJSON() error = %v", err)
+	}
+	var collection struct {
+		Features []struct {
+			Properties map[string]any
+		}
+	}
+	if err := json.Unmarshal(buf, &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+	if len(collection.Features) != 3 || collection.Features[1].Properties["url"] != "https://socialrunclubs.de/cottbus/" || collection.Features[0].Properties["nearest_club"] != "Berlin Club" {
+		t.Errorf("GeoJSON() = %s", buf)
+	}
+}
@@ -6,14 +6,15 @@ import (
 	"html/template"
 	"log"
 	"math/rand/v2"
-	"os"
-	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	googlesheetswrapper "github.com/flopp/go-googlesheetswrapper"
+	"github.com/flopp/socialrunclubs-de/internal/images"
 	"github.com/flopp/socialrunclubs-de/internal/utils"
 )
 
@@ -21,24 +22,40 @@ type City struct {
 	Name                 string
 	Clubs                []*Club
 	LatLon               *utils.LatLon
+	State                *State
+	District             string  // Landkreis; empty for district-free cities
+	Population           int     // 0 if unknown
+	AGS                  string  // Amtlicher Gemeindeschlüssel; empty if unknown
+	Area                 float64 // km²; 0 if unknown
 	NearestCities        []*City
 	NearestCitiesNoClub  []*City
+	NearbyClubs          []NearbyClub // clubs of other cities, sorted by distance
 	SizeIndexWithoutClub int
+	TagPages             []*CityTag     // tags with enough clubs for an own page, see AnnotateCityTags
+	Weekdays             []*WeekdayRuns // only weekdays with runs
+	Events               []*Event       // upcoming events, see resolveEvents
+	OtherClubs           []*Club        // clubs of other cities with a location in this city, see resolveLocations
+	Editorial            *template.HTML // optional text from the CITIES sheet
+	Summary              string         // data-driven text, see GenerateContent
+	StaticMap            *StaticMap     // set by the renderer; nil if static maps are disabled
 }
 
 func (c *City) MetaDescription() string {
 	maxLength := 160
 	desc := fmt.Sprintf("Eine Übersicht über alle Social Run Clubs in %s. ", c.Name)
-	if len(c.Clubs) == 0 {
+	clubs := c.AllClubs()
+	if len(clubs) == 0 && c.Population > 0 {
+		desc += fmt.Sprintf("%s hat %s Einwohner:innen, aber leider noch keinen eingetragenen Club. Du kannst aber gerne einen neuen Club hinzufügen!", c.Name, c.PopulationLabel())
+	} else if len(clubs) == 0 {
 		desc += "Aktuell gibt es leider keine Einträge für diese Stadt. Du kannst aber gerne einen neuen Club hinzufügen!"
-	} else if len(c.Clubs) == 1 {
-		desc += fmt.Sprintf("Aktuell gibt es einen Eintrag: %s", c.Clubs[0].Name)
+	} else if len(clubs) == 1 {
+		desc += fmt.Sprintf("Aktuell gibt es einen Eintrag: %s", clubs[0].Name)
 	} else {
-		clubNames := make([]string, 0, len(c.Clubs))
-		for _, club := range c.Clubs {
+		clubNames := make([]string, 0, len(clubs))
+		for _, club := range clubs {
 			clubNames = append(clubNames, club.Name)
 		}
-		desc += fmt.Sprintf("Aktuell gibt es %d Einträge:", len(c.Clubs))
+		desc += fmt.Sprintf("Aktuell gibt es %d Einträge:", len(clubs))
 		for i, name := range clubNames {
 			if len(desc)+len(name)+2 >= maxLength {
 				desc += "…"
@@ -56,7 +73,11 @@ func (c *City) MetaDescription() string {
 
 func (c *City) Show() bool {
 	// show 10 biggest cities without clubs & cities with run clubs
-	return c.SizeIndexWithoutClub <= 10 || len(c.Clubs) > 0
+	return c.SizeIndexWithoutClub <= 10 || c.HasClubs()
+}
+
+func (c *City) PopulationLabel() string {
+	return utils.FormatNumber(c.Population)
 }
 
 func (c *City) NumberOfClubs() int {
@@ -79,7 +100,13 @@ type Tag struct {
 	RawName     string
 	Name        string
 	Description *template.HTML
-	Clubs       []*Club
+	Summary     string // data-driven text, see GenerateContent
+	Aliases     []string
+	Parent      *Tag
+	Children    []*Tag
+	Clubs       []*Club    // including the clubs of the child tags
+	CityPages   []*CityTag // see AnnotateCityTags
+	parentRaw   string
 }
 
 func (t *Tag) Slug() string {
@@ -99,9 +126,20 @@ type Club struct {
 	Tiktok         string
 	Signal         string
 	Website        string
+	Neighbourhood  string          // Stadtteil
+	MeetingPoint   string          // Treffpunkt, e.g. "Eingang Stadtpark"
+	Runs           []Run           // regular runs, Monday first
+	Locations      []*ClubLocation // further locations, see resolveLocations
+	Events         []*Event        // upcoming events, see resolveEvents
+	Attributes     ClubAttributes
+	ImageURL       string   // manual image override
+	ImageSources   []string // custom image provider order
 	AddedRaw       string
 	UpdatedRaw     string
 	StatusRaw      string
+	ImageSet       *images.Set // set by the renderer
+	tagNames       []string    // TAGS column, resolved by resolveTags
+	StaticMap      *StaticMap  // set by the renderer; nil if static maps are disabled
 }
 
 var reParkrunUrl = regexp.MustCompile(`https?://www\.parkrun\.com\.de/([^/?]+)/*`)
@@ -153,15 +191,34 @@ func (c *Club) Image() string {
 	return fmt.Sprintf("/%s/%s/img.jpg", c.City.SanitizeName(), c.SanitizeName())
 }
 
-func (c *Club) Search() string {
-	return strings.ToLower(fmt.Sprintf("%s %s", c.Name, c.City.Name))
+func (c *Club) ShareImage() string {
+	return c.ImageSet.Path(images.Share.Name, images.JPEG.Ext)
+}
+
+// Location returns the club's meeting point, falling back to the city's coordinates.
+func (c *Club) Location() *utils.LatLon {
+	if c.LatLon != nil {
+		return c.LatLon
+	}
+	return c.City.LatLon
 }
 
-type Post struct {
-	Title        string
-	Description  string
-	Slug         string
-	TemplateFile string
+// NearbyClub is a club near a city.
+type NearbyClub struct {
+	Club     *Club
+	Distance float64 // km
+}
+
+func (n NearbyClub) DistanceLabel() string {
+	return utils.FormatDistance(n.Distance)
+}
+
+func (c *Club) Search() string {
+	search := fmt.Sprintf("%s %s", c.Name, c.City.Name)
+	for _, city := range c.LocationCities() {
+		search += " " + city.Name
+	}
+	return strings.ToLower(search)
 }
 
 type Data struct {
@@ -170,13 +227,35 @@ type Data struct {
 	Cities      []*City
 	CityMap     map[string]*City
 	Tags        []*Tag
-	TagMap      map[string]*Tag
+	TagMap      map[string]*Tag // tagKey => tag, including aliases
+	States      []*State
+	StateMap    map[string]*State
 	Clubs       []*Club
 	LatestClubs []*Club
 	TopCities   []*City
+	Weekdays    []*WeekdayRuns // runs in all of Germany, Monday first
+	Events      []*Event       // upcoming events, sorted by date
 	NumberClubs int
 	Posts       []*Post
 	Redirects   map[string]string
+	Findings    []Finding
+	Coverage    *CoverageReport // optional, rendered as internal report page
+	SearchIndex string          // path of the search index script, set by the renderer
+	locations   []*ClubLocation // LOCATIONS sheet, see resolveLocations
+}
+
+// Finding is a data problem that should be fixed in the sheets.
+type Finding struct {
+	Subject string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Subject, f.Message)
+}
+
+func (d *Data) addFinding(subject string, format string, args ...any) {
+	d.Findings = append(d.Findings, Finding{subject, fmt.Sprintf(format, args...)})
 }
 
 func (d *Data) RandomizedClubs() []*Club {
@@ -220,7 +299,7 @@ func (d *Data) getOrAddTag(name string) *Tag {
 	if d.TagMap == nil {
 		d.TagMap = make(map[string]*Tag)
 	}
-	if tag, found := d.TagMap[name]; found {
+	if tag, found := d.TagMap[tagKey(name)]; found {
 		return tag
 	}
 	tag := &Tag{
@@ -228,7 +307,7 @@ func (d *Data) getOrAddTag(name string) *Tag {
 		Name:    name,
 	}
 	d.Tags = append(d.Tags, tag)
-	d.TagMap[name] = tag
+	d.TagMap[tagKey(name)] = tag
 	return tag
 }
 
@@ -239,6 +318,32 @@ func (d *Data) redirect(from string, to string) {
 	d.Redirects[from] = to
 }
 
+// extractHeader returns the column indices of the sheet's header row; the
+// required columns must exist, the optional columns are added if they exist.
+func extractHeader(rows [][]string, required []string, optional []string) (map[string]int, error) {
+	colIdx, err := googlesheetswrapper.ExtractHeader(rows[:1], required, false)
+	if err != nil {
+		return nil, err
+	}
+	for col, name := range rows[0] {
+		name = strings.TrimSpace(name)
+		if _, found := colIdx[name]; !found && slices.Contains(optional, name) {
+			colIdx[name] = col
+		}
+	}
+	return colIdx, nil
+}
+
+// getOptionalVal returns the value of an optional column, or "" if the sheet
+// does not have the column.
+func getOptionalVal(colName string, row []string, colIdx map[string]int) string {
+	if _, ok := colIdx[colName]; !ok {
+		return ""
+	}
+	val, _ := getVal(colName, row, colIdx)
+	return val
+}
+
 func getVal(colName string, row []string, colIdx map[string]int) (string, error) {
 	col, ok := colIdx[colName]
 	if !ok {
@@ -297,7 +402,7 @@ func checkForDuplicateClubs(clubs []*Club) {
 	for _, club := range clubs {
 		key := club.Slug()
 		if _, exists := seen[key]; exists {
-			log.Printf("duplicate club found with slug: %s", key)
+			log.Printf("duplicate club found with slug: %s (use the LOCATIONS sheet for clubs with several locations)", key)
 		} else {
 			seen[key] = club
 		}
@@ -310,7 +415,8 @@ func processClubsSheet(sheetName string, rows [][]string, data *Data) error {
 	}
 
 	required := []string{"ID", "ADDED", "UPDATED", "STATUS", "REDIRECT NAME", "REDIRECT CITY", "NAME", "OLD NAME", "CITY", "COORDS", "DESCRIPTION", "TAGS", "INSTAGRAM_URL", "STRAVA_URL", "WHATSAPP_URL", "TIKTOK_URL", "WEBSITE_URL"}
-	colIdx, err := googlesheetswrapper.ExtractHeader(rows[:1], required, false)
+	optional := []string{"IMAGE_URL", "IMAGE_SOURCES", "NEIGHBOURHOOD", "WEEKDAYS", "START_TIME", "MEETING_POINT", "PACE", "DISTANCE", "LANGUAGES", "COST", "AUDIENCE"}
+	colIdx, err := extractHeader(rows, required, optional)
 	if err != nil {
 		return err
 	}
@@ -328,6 +434,14 @@ func processClubsSheet(sheetName string, rows [][]string, data *Data) error {
 		cityRaw := ""
 		latLonRaw := ""
 		tagsRaw := ""
+		imageSourcesRaw := ""
+		weekdaysRaw := ""
+		startTimeRaw := ""
+		paceRaw := ""
+		distanceRaw := ""
+		languagesRaw := ""
+		costRaw := ""
+		audienceRaw := ""
 
 		mappings := []fieldMapping{
 			{&club.Name, "NAME"},
@@ -344,6 +458,19 @@ func processClubsSheet(sheetName string, rows [][]string, data *Data) error {
 			{&club.StatusRaw, "STATUS"},
 			{&tagsRaw, "TAGS"},
 		}
+		optionalMappings := []fieldMapping{
+			{&club.Neighbourhood, "NEIGHBOURHOOD"},
+			{&club.ImageURL, "IMAGE_URL"},
+			{&imageSourcesRaw, "IMAGE_SOURCES"},
+			{&weekdaysRaw, "WEEKDAYS"},
+			{&startTimeRaw, "START_TIME"},
+			{&club.MeetingPoint, "MEETING_POINT"},
+			{&paceRaw, "PACE"},
+			{&distanceRaw, "DISTANCE"},
+			{&languagesRaw, "LANGUAGES"},
+			{&costRaw, "COST"},
+			{&audienceRaw, "AUDIENCE"},
+		}
 
 		// Process direct field assignments
 		for _, mapping := range mappings {
@@ -353,6 +480,9 @@ func processClubsSheet(sheetName string, rows [][]string, data *Data) error {
 				*mapping.field = val
 			}
 		}
+		for _, mapping := range optionalMappings {
+			*mapping.field = getOptionalVal(mapping.col, row, colIdx)
+		}
 
 		// skip invalid clubs
 		if club.Name == "" {
@@ -419,6 +549,8 @@ func processClubsSheet(sheetName string, rows [][]string, data *Data) error {
 			club.LatLon = &latlon
 		}
 
+		club.ImageSources = utils.SplitAndTrim(strings.ToLower(imageSourcesRaw), ",")
+
 		if strings.Contains(club.Whatsapp, "signal") {
 			club.Signal = club.Whatsapp
 			club.Whatsapp = ""
@@ -445,13 +577,17 @@ func processClubsSheet(sheetName string, rows [][]string, data *Data) error {
 			club.City = city
 		}
 
-		// process tags
-		club.Tags = make([]*Tag, 0)
-		for _, tagName := range utils.SplitAndTrim(tagsRaw, ",") {
-			tag := data.getOrAddTag(tagName)
-			tag.Clubs = append(tag.Clubs, club)
-			club.Tags = append(club.Tags, tag)
+		if club.Runs, err = parseRuns(weekdaysRaw, startTimeRaw); err != nil {
+			data.addFinding(club.Name, "CLUBS row %d: %v", index+2, err)
 		}
+		var attributeErrs []error
+		club.Attributes, attributeErrs = parseClubAttributes(paceRaw, distanceRaw, languagesRaw, costRaw, audienceRaw)
+		for _, err := range attributeErrs {
+			data.addFinding(club.Name, "CLUBS row %d: %v", index+2, err)
+		}
+
+		// tags are resolved after all sheets are processed, see resolveTags
+		club.tagNames = utils.SplitAndTrim(tagsRaw, ",")
 	}
 
 	return nil
@@ -463,16 +599,27 @@ func processCitiesSheet(sheetName string, rows [][]string, data *Data) error {
 	}
 
 	required := []string{"NAME"}
-	colIdx, err := googlesheetswrapper.ExtractHeader(rows[:1], required, false)
+	optional := []string{"COORDS", "STATE", "DISTRICT", "POPULATION", "AGS", "TEXT"}
+	colIdx, err := extractHeader(rows, required, optional)
 	if err != nil {
 		return err
 	}
 
 	cities := make(map[string]struct{})
 	cityList := make([]string, 0)
+	cityCoords := make(map[string]utils.LatLon)
+	cityRegions := make(map[string]utils.Region)
+	cityPopulations := make(map[string]int)
+	cityKeys := make(map[string]string)
+	cityTexts := make(map[string]string)
 
 	for index, row := range rows[1:] {
 		name := ""
+		latLonRaw := ""
+		populationRaw := ""
+		ags := ""
+		text := ""
+		region := utils.Region{}
 
 		if name, err = getVal("NAME", row, colIdx); err != nil {
 			return fmt.Errorf("row %d: %v", index+2, err)
@@ -480,6 +627,12 @@ func processCitiesSheet(sheetName string, rows [][]string, data *Data) error {
 		if name == "" {
 			continue
 		}
+		latLonRaw = getOptionalVal("COORDS", row, colIdx)
+		region.State = getOptionalVal("STATE", row, colIdx)
+		region.District = getOptionalVal("DISTRICT", row, colIdx)
+		populationRaw = getOptionalVal("POPULATION", row, colIdx)
+		ags = getOptionalVal("AGS", row, colIdx)
+		text = getOptionalVal("TEXT", row, colIdx)
 
 		if _, found := cities[name]; !found {
 			cities[name] = struct{}{}
@@ -487,6 +640,32 @@ func processCitiesSheet(sheetName string, rows [][]string, data *Data) error {
 		} else {
 			log.Printf("CITIES row %d: duplicate city name: %q", index+2, name)
 		}
+
+		cityRegions[name] = region
+		cityKeys[name] = ags
+		if text != "" {
+			cityTexts[name] = text
+		}
+
+		if populationRaw != "" {
+			// allow German thousands separators, e.g. "52.300"
+			population, err := strconv.Atoi(strings.NewReplacer(".", "", " ", "").Replace(populationRaw))
+			if err != nil || population < 0 {
+				data.addFinding(name, "CITIES row %d: invalid population: %q", index+2, populationRaw)
+			} else {
+				cityPopulations[name] = population
+			}
+		}
+
+		// manually entered coordinates take precedence over geocoding
+		if latLonRaw != "" {
+			latlon, err := utils.ParseLatLon(latLonRaw)
+			if err != nil {
+				data.addFinding(name, "CITIES row %d: invalid coords: %q", index+2, latLonRaw)
+				continue
+			}
+			cityCoords[name] = latlon
+		}
 	}
 
 	// if there are already city objects (from clubs), check the are all in the cities list
@@ -509,7 +688,24 @@ func processCitiesSheet(sheetName string, rows [][]string, data *Data) error {
 			data.CityMap[city.Name] = city
 			indexWithoutClub++
 		}
+		city := data.CityMap[name]
+		if latlon, found := cityCoords[name]; found {
+			city.LatLon = &latlon
+		}
+		city.Population = cityPopulations[name]
+		city.AGS = cityKeys[name]
+		if text, found := cityTexts[name]; found {
+			editorial := template.HTML(text)
+			city.Editorial = &editorial
+		}
+		// manually entered regions take precedence over reverse geocoding
+		region := cityRegions[name]
+		city.District = region.District
+		if region.State != "" {
+			data.setCityState(city, region.State)
+		}
 	}
+	sortStates(data.States)
 
 	return nil
 }
@@ -520,7 +716,8 @@ func processTagsSheet(sheetName string, rows [][]string, data *Data) error {
 	}
 
 	required := []string{"NAME", "FANCY", "DESCRIPTION"}
-	colIdx, err := googlesheetswrapper.ExtractHeader(rows[:1], required, false)
+	optional := []string{"ALIASES", "PARENT"}
+	colIdx, err := extractHeader(rows, required, optional)
 	if err != nil {
 		return err
 	}
@@ -553,53 +750,11 @@ func processTagsSheet(sheetName string, rows [][]string, data *Data) error {
 			descriptionHtml := template.HTML(descriptionRaw)
 			tag.Description = &descriptionHtml
 		}
-	}
-
-	return nil
-}
-
-func collectPosts(data *Data) error {
-	data.Posts = make([]*Post, 0)
-
-	files, err := filepath.Glob("templates/posts/*.html")
-	if err != nil {
-		return err
-	}
-
-	for _, file := range files {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("reading post file %s: %v", file, err)
-		}
-
-		title := ""
-		re := regexp.MustCompile(`<!--\s*TITLE\s+"([^"]+)"\s*-->`)
-		matches := re.FindStringSubmatch(string(content))
-		if len(matches) == 0 {
-			return fmt.Errorf("post file %s: no TITLE found", file)
+		aliasesRaw := getOptionalVal("ALIASES", row, colIdx)
+		for _, alias := range utils.SplitAndTrim(aliasesRaw, ",") {
+			data.addTagAlias(tag, alias)
 		}
-
-		if len(matches) > 1 {
-			title = matches[1]
-		}
-
-		description := ""
-		reDesc := regexp.MustCompile(`<!--\s*DESCRIPTION\s+"([^"]+)"\s*-->`)
-		descMatches := reDesc.FindStringSubmatch(string(content))
-		if len(descMatches) > 1 {
-			description = descMatches[1]
-		}
-
-		filename := filepath.Base(file)
-		slug := "/post/" + strings.TrimSuffix(filename, filepath.Ext(filename)) + "/"
-		templateName := "posts/" + filename
-
-		data.Posts = append(data.Posts, &Post{
-			Title:        title,
-			Description:  description,
-			Slug:         slug,
-			TemplateFile: templateName,
-		})
+		tag.parentRaw = getOptionalVal("PARENT", row, colIdx)
 	}
 
 	return nil
@@ -631,7 +786,7 @@ func GetData(config Config) (*Data, error) {
 	}
 
 	// get the clubs and cities sheets
-	var clubsFound, citiesFound, tagsFound bool
+	var clubsFound, citiesFound, tagsFound, eventsFound, locationsFound bool
 
 	// Define sheet processors
 	type sheetProcessor struct {
@@ -640,9 +795,11 @@ func GetData(config Config) (*Data, error) {
 	}
 
 	processors := map[string]*sheetProcessor{
-		"CLUBS":  {processFunc: processClubsSheet, found: &clubsFound},
-		"CITIES": {processFunc: processCitiesSheet, found: &citiesFound},
-		"TAGS":   {processFunc: processTagsSheet, found: &tagsFound},
+		"CLUBS":     {processFunc: processClubsSheet, found: &clubsFound},
+		"CITIES":    {processFunc: processCitiesSheet, found: &citiesFound},
+		"TAGS":      {processFunc: processTagsSheet, found: &tagsFound},
+		"EVENTS":    {processFunc: processEventsSheet, found: &eventsFound},       // optional
+		"LOCATIONS": {processFunc: processLocationsSheet, found: &locationsFound}, // optional
 	}
 
 	// Process sheets
@@ -668,6 +825,8 @@ func GetData(config Config) (*Data, error) {
 		}
 	}
 
+	resolveTags(data)
+
 	// sorting of cities
 	sortCitiesAndClubs(data.Cities)
 
@@ -681,6 +840,10 @@ func GetData(config Config) (*Data, error) {
 	}
 	sortClubs(data.Clubs)
 
+	resolveLocations(data)
+	collectWeekdayRuns(data)
+	resolveEvents(data)
+
 	// check for duplicates (via slugs)
 	checkForDuplicateClubs(data.Clubs)
 
@@ -722,13 +885,13 @@ func GetData(config Config) (*Data, error) {
 	// get the 5 cities with the most clubs:
 	var topCities []*City
 	for _, city := range data.Cities {
-		if len(city.Clubs) > 0 {
+		if city.HasClubs() {
 			topCities = append(topCities, city)
 		}
 	}
 	sort.Slice(topCities, func(i, j int) bool {
-		ic := len(topCities[i].Clubs)
-		jc := len(topCities[j].Clubs)
+		ic := len(topCities[i].AllClubs())
+		jc := len(topCities[j].AllClubs())
 		if ic != jc {
 			return ic > jc
 		}
@@ -740,78 +903,92 @@ func GetData(config Config) (*Data, error) {
 		data.TopCities = topCities
 	}
 
-	if err := collectPosts(data); err != nil {
+	if err := collectPosts(data, !config.IsRemoteTarget); err != nil {
 		return nil, fmt.Errorf("collecting posts: %v", err)
 	}
 
 	return data, nil
 }
 
-func AnnotateCityCoordinates(data *Data, geocoder *utils.CachingGeocoder) error {
+// AnnotateCityCoordinates geocodes all cities without manually entered coordinates;
+// failed lookups are recorded as findings.
+func AnnotateCityCoordinates(data *Data, geocoder utils.Geocoder) error {
 	for _, city := range data.Cities {
 		if city.LatLon != nil {
 			continue
 		}
-		/*
-			if city.Show() == false {
-				continue
-			}
-		*/
-		coords, err := geocoder.Lookup(city.Name)
+		result, err := geocoder.Lookup(city.Name)
 		if err != nil {
-			log.Panicf("getting coordinates for city %q: %v", city.Name, err)
+			data.addFinding(city.Name, "getting coordinates: %v", err)
 		} else {
-			city.LatLon = &coords
+			city.LatLon = &result.LatLon
 		}
 	}
 
 	return nil
 }
 
-func findNearestCities(city *City, cities []*City, maxResults int, withClubs bool) []*City {
+func cityLocation(city *City) *utils.LatLon {
+	return city.LatLon
+}
+
+func findNearestCities(city *City, index *utils.SpatialIndex[*City], maxResults int, withClubs bool) []*City {
 	if city.LatLon == nil {
 		return nil
 	}
 
-	type cityDistance struct {
-		city     *City
-		distance float64
-	}
-	var distances []cityDistance
-	for _, other := range cities {
-		if other == city {
-			continue
-		}
-		if other.LatLon == nil {
-			continue
-		}
-		if withClubs && len(other.Clubs) == 0 {
-			continue
-		}
-		if !withClubs && len(other.Clubs) != 0 {
-			continue
-		}
-
-		distance := utils.Distance(*city.LatLon, *other.LatLon)
-		distances = append(distances, cityDistance{city: other, distance: distance})
-	}
-	sort.Slice(distances, func(i, j int) bool {
-		return distances[i].distance < distances[j].distance
+	neighbours := index.Nearest(*city.LatLon, maxResults, func(other *City) bool {
+		return other != city && other.HasClubs() == withClubs
 	})
-	if len(distances) > maxResults {
-		distances = distances[:maxResults]
-	}
 	var result []*City
-	for _, d := range distances {
-		result = append(result, d.city)
+	for _, n := range neighbours {
+		result = append(result, n.Item)
 	}
 	return result
 }
 
 func AnnotateNearestCities(data *Data) error {
+	index := utils.NewSpatialIndex(data.Cities, cityLocation)
+	for _, city := range data.Cities {
+		city.NearestCities = findNearestCities(city, index, 3, true)
+		city.NearestCitiesNoClub = findNearestCities(city, index, 3, false)
+	}
+	return nil
+}
+
+const (
+	defaultNearbyRadiusKM = 25
+	defaultNearbyMaxClubs = 10
+)
+
+// AnnotateNearbyClubs determines the clubs of other cities within the configured
+// radius of each city.
+func AnnotateNearbyClubs(data *Data, config Config) error {
+	radius := config.NearbyClubs.RadiusKM
+	if radius <= 0 {
+		radius = defaultNearbyRadiusKM
+	}
+	maxClubs := config.NearbyClubs.MaxClubs
+	if maxClubs <= 0 {
+		maxClubs = defaultNearbyMaxClubs
+	}
+
+	index := newClubIndex(data.Clubs)
 	for _, city := range data.Cities {
-		city.NearestCities = findNearestCities(city, data.Cities, 3, true)
-		city.NearestCitiesNoClub = findNearestCities(city, data.Cities, 3, false)
+		city.NearbyClubs = nil
+		if city.LatLon == nil {
+			continue
+		}
+		// clubs with a location in the city are already listed on the city page
+		neighbours := uniqueClubs(index.WithinRadius(*city.LatLon, radius, func(p clubPosition) bool {
+			return p.Club.City != city && !slices.Contains(city.OtherClubs, p.Club)
+		}))
+		if len(neighbours) > maxClubs {
+			neighbours = neighbours[:maxClubs]
+		}
+		for _, n := range neighbours {
+			city.NearbyClubs = append(city.NearbyClubs, NearbyClub{n.Item, n.Distance})
+		}
 	}
 	return nil
 }
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestAnnotateNearbyClubs(t *testing.T) {
+	berlin := &City{Name: "Berlin", LatLon: &utils.LatLon{Lat: 52.52, Lon: 13.405}}
+	potsdam := &City{Name: "Potsdam", LatLon: &utils.LatLon{Lat: 52.39, Lon: 13.065}}
+	hamburg := &City{Name: "Hamburg", LatLon: &utils.LatLon{Lat: 53.55, Lon: 9.99}}
+	nowhere := &City{Name: "Nowhere"}
+
+	clubs := []*Club{
+		{Name: "Berlin Club", City: berlin},
+		{Name: "Potsdam Club", City: potsdam},
+		{Name: "Wannsee Club", City: potsdam, LatLon: &utils.LatLon{Lat: 52.42, Lon: 13.17}},
+		{Name: "Hamburg Club", City: hamburg},
+		{Name: "Nowhere Club", City: nowhere},
+	}
+	for _, club := range clubs {
+		club.City.Clubs = append(club.City.Clubs, club)
+	}
+	data := &Data{Cities: []*City{berlin, potsdam, hamburg, nowhere}, Clubs: clubs}
+
+	var config Config
+	config.NearbyClubs.RadiusKM = 30
+	if err := AnnotateNearbyClubs(data, config); err != nil {
+		t.Fatalf("AnnotateNearbyClubs() error = %v", err)
+	}
+
+	names := func(city *City) []string {
+		result := make([]string, 0)
+		for _, n := range city.NearbyClubs {
+			result = append(result, n.Club.Name)
+		}
+		return result
+	}
+	if got := names(berlin); !reflect.DeepEqual(got, []string{"Wannsee Club", "Potsdam Club"}) {
+		t.Errorf("Berlin: NearbyClubs = %v", got)
+	}
+	if got := names(potsdam); !reflect.DeepEqual(got, []string{"Berlin Club"}) {
+		t.Errorf("Potsdam: NearbyClubs = %v", got)
+	}
+	if got := names(hamburg); len(got) != 0 {
+		t.Errorf("Hamburg: NearbyClubs = %v", got)
+	}
+	if got := names(nowhere); len(got) != 0 {
+		t.Errorf("Nowhere: NearbyClubs = %v", got)
+	}
+	if label := berlin.NearbyClubs[1].DistanceLabel(); label != "27 km" {
+		t.Errorf("DistanceLabel() = %q", label)
+	}
+
+	config.NearbyClubs.MaxClubs = 1
+	if err := AnnotateNearbyClubs(data, config); err != nil {
+		t.Fatalf("AnnotateNearbyClubs() error = %v", err)
+	}
+	if got := names(berlin); !reflect.DeepEqual(got, []string{"Wannsee Club"}) {
+		t.Errorf("Berlin with MaxClubs=1: NearbyClubs = %v", got)
+	}
+}
+
+func TestProcessSheetsWithoutOptionalColumns(t *testing.T) {
+	data := &Data{CityMap: make(map[string]*City)}
+	cities := [][]string{
+		{"NAME"},
+		{"Berlin"},
+	}
+	if err := processCitiesSheet("CITIES", cities, data); err != nil {
+		t.Fatalf("processCitiesSheet() error = %v", err)
+	}
+	clubs := [][]string{
+		{"ID", "ADDED", "UPDATED", "STATUS", "REDIRECT NAME", "REDIRECT CITY", "NAME", "OLD NAME", "CITY", "COORDS", "DESCRIPTION", "TAGS", "INSTAGRAM_URL", "STRAVA_URL", "WHATSAPP_URL", "TIKTOK_URL", "WEBSITE_URL"},
+		{"1", "2025-01-01", "", "", "", "", "Berlin Run Club", "", "Berlin", "", "", "", "", "", "", "", ""},
+	}
+	if err := processClubsSheet("CLUBS", clubs, data); err != nil {
+		t.Fatalf("processClubsSheet() error = %v", err)
+	}
+	tags := [][]string{
+		{"NAME", "FANCY", "DESCRIPTION"},
+		{"kaffee", "Kaffee", ""},
+	}
+	if err := processTagsSheet("TAGS", tags, data); err != nil {
+		t.Fatalf("processTagsSheet() error = %v", err)
+	}
+	berlin := data.CityMap["Berlin"]
+	if len(berlin.Clubs) != 1 || berlin.Clubs[0].Name != "Berlin Run Club" {
+		t.Errorf("Berlin: Clubs = %+v", berlin.Clubs)
+	}
+}
@@ -0,0 +1,240 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // the event times are local German times, independent of the build machine
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// eventDuration is the assumed duration of events with start time, e.g. for calendar entries.
+const eventDuration = 2 * time.Hour
+
+var germanTime = mustLoadLocation("Europe/Berlin")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Event is a one-off event, e.g. a launch party, a charity run or a group
+// trip to a race.
+type Event struct {
+	Name        string
+	Description string
+	Start       time.Time // German local time; midnight for events without start time
+	HasTime     bool
+	Club        *Club // organizing club; nil if unknown
+	City        *City // the event's city, by default the club's city
+	Location    string
+	URL         string
+	clubRaw     string
+	cityRaw     string
+}
+
+// ID identifies the event, e.g. for anchors and calendar entries.
+func (e *Event) ID() string {
+	id := fmt.Sprintf("%s-%s", e.Start.Format("2006-01-02"), utils.SanitizeName(e.Name))
+	if e.Club != nil {
+		id += "-" + e.Club.SanitizeName()
+	}
+	return id
+}
+
+// Slug is the event's anchor on the events page.
+func (e *Event) Slug() string {
+	return "/termine.html#" + e.ID()
+}
+
+// End is the assumed end of the event, see eventDuration; events without
+// start time last the whole day.
+func (e *Event) End() time.Time {
+	if !e.HasTime {
+		return e.Start.AddDate(0, 0, 1)
+	}
+	return e.Start.Add(eventDuration)
+}
+
+// DateLabel returns the German date (and time), e.g. "Sa, 5. Dezember 2026, 18:30 Uhr".
+func (e *Event) DateLabel() string {
+	label := fmt.Sprintf("%s, %s", weekdayNames[e.Start.Weekday()][:2], utils.FormatDate(e.Start))
+	if e.HasTime {
+		label += e.Start.Format(", 15:04 Uhr")
+	}
+	return label
+}
+
+func (e *Event) DateISO() string {
+	if e.HasTime {
+		return e.Start.Format(time.RFC3339)
+	}
+	return e.Start.Format("2006-01-02")
+}
+
+var reGermanDate = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{4})$`)
+
+// parseEventDate parses dates like "2026-12-05" or "5.12.2026".
+func parseEventDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if match := reGermanDate.FindStringSubmatch(s); match != nil {
+		day, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		year, _ := strconv.Atoi(match[3])
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, germanTime)
+		if date.Day() != day || int(date.Month()) != month {
+			return time.Time{}, fmt.Errorf("invalid date: %q", s)
+		}
+		return date, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", s, germanTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %q", s)
+	}
+	return date, nil
+}
+
+func processEventsSheet(sheetName string, rows [][]string, data *Data) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("sheet is empty")
+	}
+
+	required := []string{"NAME", "DATE"}
+	optional := []string{"TIME", "CLUB", "CITY", "LOCATION", "URL", "DESCRIPTION"}
+	colIdx, err := extractHeader(rows, required, optional)
+	if err != nil {
+		return err
+	}
+
+	for index, row := range rows[1:] {
+		event := &Event{}
+		dateRaw := ""
+		timeRaw := ""
+
+		if event.Name, err = getVal("NAME", row, colIdx); err != nil {
+			return fmt.Errorf("row %d: %v", index+2, err)
+		}
+		if dateRaw, err = getVal("DATE", row, colIdx); err != nil {
+			return fmt.Errorf("row %d: %v", index+2, err)
+		}
+		optionalMappings := []struct {
+			field *string
+			col   string
+		}{
+			{&timeRaw, "TIME"},
+			{&event.clubRaw, "CLUB"},
+			{&event.cityRaw, "CITY"},
+			{&event.Location, "LOCATION"},
+			{&event.URL, "URL"},
+			{&event.Description, "DESCRIPTION"},
+		}
+		for _, mapping := range optionalMappings {
+			*mapping.field = getOptionalVal(mapping.col, row, colIdx)
+		}
+
+		if event.Name == "" {
+			if dateRaw != "" {
+				log.Printf("EVENTS row %d: empty event name", index+2)
+			}
+			continue
+		}
+		if event.Start, err = parseEventDate(dateRaw); err != nil {
+			data.addFinding(event.Name, "EVENTS row %d: %v", index+2, err)
+			continue
+		}
+		if timeRaw != "" {
+			start, err := parseStartTime(timeRaw)
+			if err != nil {
+				data.addFinding(event.Name, "EVENTS row %d: %v", index+2, err)
+				continue
+			}
+			clock, _ := time.Parse("15:04", start)
+			event.Start = time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), clock.Hour(), clock.Minute(), 0, 0, germanTime)
+			event.HasTime = true
+		}
+		data.Events = append(data.Events, event)
+	}
+
+	return nil
+}
+
+// findClub finds a club by name; "City/Name" resolves ambiguous names.
+func (d *Data) findClub(ref string) (*Club, error) {
+	cityName, clubName, hasCity := strings.Cut(ref, "/")
+	if !hasCity {
+		clubName = cityName
+	}
+	var found *Club
+	for _, club := range d.Clubs {
+		if club.SanitizeName() != utils.SanitizeName(clubName) || (hasCity && club.City.SanitizeName() != utils.SanitizeName(cityName)) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous club %q, use \"City/Club\"", ref)
+		}
+		found = club
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown club %q", ref)
+	}
+	return found, nil
+}
+
+// resolveEvents links the events of the EVENTS sheet to their clubs and
+// cities, drops past events (before the day of data.Now) and sorts the rest
+// by date.
+func resolveEvents(data *Data) {
+	today := data.Now.In(germanTime)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, germanTime)
+
+	upcoming := make([]*Event, 0, len(data.Events))
+	for _, event := range data.Events {
+		if event.clubRaw != "" {
+			club, err := data.findClub(event.clubRaw)
+			if err != nil {
+				data.addFinding(event.Name, "EVENTS: %v", err)
+			} else {
+				event.Club = club
+				event.City = club.City
+			}
+		}
+		if event.cityRaw != "" {
+			if city, found := data.CityMap[event.cityRaw]; found {
+				event.City = city
+			} else {
+				data.addFinding(event.Name, "EVENTS: unknown city %q", event.cityRaw)
+			}
+		}
+		if event.Club == nil && event.City == nil {
+			if event.clubRaw == "" && event.cityRaw == "" {
+				data.addFinding(event.Name, "EVENTS: neither club nor city")
+			}
+			continue
+		}
+		if event.Start.Before(today) {
+			continue
+		}
+		upcoming = append(upcoming, event)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Start.Before(upcoming[j].Start)
+	})
+	data.Events = upcoming
+	for _, event := range data.Events {
+		if event.Club != nil {
+			event.Club.Events = append(event.Club.Events, event)
+		}
+		if event.City != nil {
+			event.City.Events = append(event.City.Events, event)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEventDate(t *testing.T) {
+	for _, s := range []string{"2026-12-05", "5.12.2026", " 05.12.2026 "} {
+		date, err := parseEventDate(s)
+		if err != nil || !date.Equal(time.Date(2026, 12, 5, 0, 0, 0, 0, germanTime)) {
+			t.Errorf("parseEventDate(%q) = %v, %v", s, date, err)
+		}
+	}
+	for _, s := range []string{"", "31.02.2026", "2026-13-01", "morgen"} {
+		if _, err := parseEventDate(s); err == nil {
+			t.Errorf("parseEventDate(%q): expected error", s)
+		}
+	}
+}
+
+func TestProcessEventsSheet(t *testing.T) {
+	data := &Data{}
+	rows := [][]string{
+		{"NAME", "DATE", "TIME", "CLUB", "CITY", "LOCATION", "URL", "DESCRIPTION"},
+		{"Launch Party", "2026-03-28", "18.30", "A", "", "Park", "", ""},
+		{"Race Trip", "4.4.2026", "", "A", "", "", "", ""},
+		{"Bad Time", "2026-04-04", "abends", "A", "", "", "", ""},
+	}
+	if err := processEventsSheet("EVENTS", rows, data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Events) != 2 || len(data.Findings) != 1 {
+		t.Fatalf("events = %v, findings = %v", data.Events, data.Findings)
+	}
+	// 2026-03-28 is the day before the switch to summer time
+	launch := data.Events[0]
+	if !launch.HasTime || launch.Start.Hour() != 18 || launch.Start.Minute() != 30 || launch.DateISO() != "2026-03-28T18:30:00+01:00" {
+		t.Errorf("launch = %v", launch.Start)
+	}
+	if label := launch.DateLabel(); label != "Sa, 28. März 2026, 18:30 Uhr" {
+		t.Errorf("DateLabel() = %q", label)
+	}
+	if trip := data.Events[1]; trip.HasTime || trip.DateISO() != "2026-04-04" || trip.DateLabel() != "Sa, 4. April 2026" {
+		t.Errorf("trip = %v, %q", trip.Start, trip.DateLabel())
+	}
+
+	// all columns but NAME and DATE are optional
+	data = &Data{}
+	if err := processEventsSheet("EVENTS", [][]string{{"NAME", "DATE"}, {"Stadtlauf", "2026-05-01"}}, data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Events) != 1 || data.Events[0].HasTime {
+		t.Errorf("events = %v", data.Events)
+	}
+}
+
+func TestResolveEvents(t *testing.T) {
+	leipzig := &City{Name: "Leipzig"}
+	halle := &City{Name: "Halle"}
+	a := &Club{Name: "A", City: leipzig}
+	b := &Club{Name: "B", City: leipzig}
+	b2 := &Club{Name: "B", City: halle}
+	data := &Data{
+		Now:     time.Date(2026, 5, 10, 23, 30, 0, 0, time.UTC), // May 11th in Germany
+		Clubs:   []*Club{a, b, b2},
+		CityMap: map[string]*City{"Leipzig": leipzig, "Halle": halle},
+	}
+	day := func(d int) time.Time { return time.Date(2026, 5, d, 0, 0, 0, 0, germanTime) }
+	data.Events = []*Event{
+		{Name: "Later", Start: day(20), clubRaw: "A"},
+		{Name: "Today", Start: day(11), clubRaw: "Halle/B"},
+		{Name: "Past", Start: day(10), clubRaw: "A"},
+		{Name: "Trip", Start: day(15), clubRaw: "A", cityRaw: "Halle"},
+		{Name: "Ambiguous", Start: day(15), clubRaw: "B"},
+		{Name: "City only", Start: day(12), cityRaw: "Leipzig"},
+	}
+
+	resolveEvents(data)
+
+	names := make([]string, 0)
+	for _, event := range data.Events {
+		names = append(names, event.Name)
+	}
+	if strings.Join(names, ",") != "Today,City only,Trip,Later" {
+		t.Errorf("events = %v", names)
+	}
+	if len(data.Findings) != 1 || !strings.Contains(data.Findings[0].String(), "ambiguous") {
+		t.Errorf("findings = %v", data.Findings)
+	}
+	if len(a.Events) != 2 || len(b2.Events) != 1 || len(b.Events) != 0 {
+		t.Errorf("club events = %d, %d, %d", len(a.Events), len(b2.Events), len(b.Events))
+	}
+	if len(leipzig.Events) != 2 || len(halle.Events) != 2 || halle.Events[1].Name != "Trip" {
+		t.Errorf("city events = %v, %v", leipzig.Events, halle.Events)
+	}
+	if id := data.Events[0].ID(); id != "2026-05-11-today-b" {
+		t.Errorf("ID() = %q", id)
+	}
+}
+
+func TestBuildICal(t *testing.T) {
+	berlin := &City{Name: "Berlin"}
+	club := &Club{Name: "Berlin Run Club", City: berlin}
+	events := []*Event{
+		{Name: "Launch Party", Start: time.Date(2026, 7, 4, 18, 30, 0, 0, germanTime), HasTime: true, Club: club, City: berlin, Location: "Tempelhofer Feld", Description: "Laufen, dann Kaffee; alle willkommen. " + strings.Repeat("Sehr schön! ", 8)},
+		{Name: "Race Trip", Start: time.Date(2026, 7, 5, 0, 0, 0, 0, germanTime), City: berlin, URL: "https://example.com"},
+	}
+	ical := string(buildICal("Termine", events, time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)))
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:2026-07-04-launch-party-berlin-run-club@socialrunclubs.de\r\n",
+		"DTSTAMP:20260701T120000Z\r\n",
+		"DTSTART:20260704T163000Z\r\nDTEND:20260704T183000Z\r\n",
+		"SUMMARY:Launch Party (Berlin Run Club)\r\n",
+		"DESCRIPTION:Laufen\\, dann Kaffee\\; alle willkommen.",
+		"LOCATION:Tempelhofer Feld\\, Berlin\r\n",
+		"URL:https://socialrunclubs.de/termine.html#2026-07-04-launch-party-berlin-r\r\n un-club\r\n",
+		"DTSTART;VALUE=DATE:20260705\r\nDTEND;VALUE=DATE:20260706\r\n",
+		"URL:https://example.com\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ical, expected) {
+			t.Errorf("iCal does not contain %q:\n%s", expected, ical)
+		}
+	}
+	for _, line := range strings.Split(ical, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+}
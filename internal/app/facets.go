@@ -0,0 +1,172 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// FacetOption is a value of a facet, e.g. the tag "Trail" of the facet "tag".
+type FacetOption struct {
+	Value string // used in the URL query, e.g. "?tag=trail,kaffee"
+	Label string
+	Count int // number of matching clubs
+	rank  int // display order of facets with fixed order, e.g. weekdays
+}
+
+// Facet is a group of filter options of club lists; clubs match a facet if
+// they match one of the selected options, and the filter if they match all
+// facets with selected options.
+type Facet struct {
+	Key     string
+	Name    string
+	Options []FacetOption
+}
+
+// facetDefinition describes how to get the options of a facet for a club.
+type facetDefinition struct {
+	key     string
+	name    string
+	byCount bool // sort the options by count instead of rank
+	options func(club *Club) []FacetOption
+}
+
+func attributeOptions(vocabulary []AttributeValue, values []AttributeValue) []FacetOption {
+	options := make([]FacetOption, 0, len(values))
+	for _, value := range values {
+		for rank, v := range vocabulary {
+			if v.Key == value.Key {
+				options = append(options, FacetOption{Value: value.Key, Label: value.Label, rank: rank})
+			}
+		}
+	}
+	return options
+}
+
+var facetDefinitions = []facetDefinition{
+	{"tag", "Kategorie", true, func(club *Club) []FacetOption {
+		seen := make(map[*Tag]bool)
+		options := make([]FacetOption, 0)
+		for _, tag := range club.Tags {
+			for _, t := range append([]*Tag{tag}, tag.Ancestors()...) {
+				if !seen[t] {
+					seen[t] = true
+					options = append(options, FacetOption{Value: utils.SanitizeName(t.RawName), Label: t.Name})
+				}
+			}
+		}
+		return options
+	}},
+	{"day", "Wochentag", false, func(club *Club) []FacetOption {
+		options := make([]FacetOption, 0)
+		for _, day := range club.Weekdays() {
+			options = append(options, FacetOption{Value: utils.SanitizeName(weekdayNames[day]), Label: weekdayNames[day], rank: int(day+6) % 7})
+		}
+		return options
+	}},
+	{"cost", "Kosten", false, func(club *Club) []FacetOption {
+		if club.Attributes.Cost == nil {
+			return nil
+		}
+		return attributeOptions(costValues, []AttributeValue{*club.Attributes.Cost})
+	}},
+	{"pace", "Tempo", false, func(club *Club) []FacetOption {
+		options := make([]FacetOption, 0, 2)
+		for _, pace := range club.Attributes.Paces {
+			if pace.Min < fastPace {
+				options = append(options, FacetOption{Value: "schnell", Label: fmt.Sprintf("schneller als %s min/km", formatPace(fastPace))})
+				break
+			}
+		}
+		for _, pace := range club.Attributes.Paces {
+			if pace.Max >= relaxedPace {
+				options = append(options, FacetOption{Value: "gemuetlich", Label: fmt.Sprintf("ab %s min/km", formatPace(relaxedPace)), rank: 1})
+				break
+			}
+		}
+		return options
+	}},
+	{"audience", "Zielgruppe", false, func(club *Club) []FacetOption {
+		return attributeOptions(audienceValues, club.Attributes.Audiences)
+	}},
+	{"lang", "Sprache", false, func(club *Club) []FacetOption {
+		return attributeOptions(languageValues, club.Attributes.Languages)
+	}},
+	{"state", "Bundesland", true, func(club *Club) []FacetOption {
+		seen := make(map[*State]bool)
+		options := make([]FacetOption, 0, 1)
+		for _, city := range append([]*City{club.City}, club.LocationCities()...) {
+			if city.State != nil && !seen[city.State] {
+				seen[city.State] = true
+				options = append(options, FacetOption{Value: utils.SanitizeName(city.State.Name), Label: city.State.Name})
+			}
+		}
+		return options
+	}},
+	{"city", "Stadt", true, func(club *Club) []FacetOption {
+		options := make([]FacetOption, 0, 1)
+		for _, city := range append([]*City{club.City}, club.LocationCities()...) {
+			options = append(options, FacetOption{Value: city.SanitizeName(), Label: city.Name})
+		}
+		return options
+	}},
+}
+
+// Facets returns the club's facet values for the data-facets attribute of
+// club lists, e.g. "tag:trail day:dienstag city:leipzig".
+func (c *Club) Facets() string {
+	values := make([]string, 0)
+	for _, definition := range facetDefinitions {
+		for _, option := range definition.options(c) {
+			values = append(values, definition.key+":"+option.Value)
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+// buildFacets collects the facets of the clubs with their options and counts.
+// Facets without choice (no options, or one option matching all clubs) are
+// skipped.
+func buildFacets(clubs []*Club) []*Facet {
+	facets := make([]*Facet, 0, len(facetDefinitions))
+	for _, definition := range facetDefinitions {
+		facet := &Facet{Key: definition.key, Name: definition.name}
+		index := make(map[string]int)
+		for _, club := range clubs {
+			for _, option := range definition.options(club) {
+				i, found := index[option.Value]
+				if !found {
+					i = len(facet.Options)
+					index[option.Value] = i
+					facet.Options = append(facet.Options, option)
+				}
+				facet.Options[i].Count++
+			}
+		}
+		if len(facet.Options) == 0 || (len(facet.Options) == 1 && facet.Options[0].Count == len(clubs)) {
+			continue
+		}
+		sort.SliceStable(facet.Options, func(i, j int) bool {
+			a, b := facet.Options[i], facet.Options[j]
+			if definition.byCount {
+				if a.Count != b.Count {
+					return a.Count > b.Count
+				}
+				return a.Value < b.Value
+			}
+			return a.rank < b.rank
+		})
+		facets = append(facets, facet)
+	}
+	return facets
+}
+
+func (c *City) ClubFacets() []*Facet {
+	return buildFacets(c.AllClubs())
+}
+
+func (d *Data) ClubFacets() []*Facet {
+	return buildFacets(d.Clubs)
+}
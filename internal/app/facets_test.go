@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBuildFacets(t *testing.T) {
+	data := &Data{}
+	leipzig := &City{Name: "Leipzig"}
+	halle := &City{Name: "Halle"}
+	data.Cities = []*City{leipzig, halle}
+	data.setCityState(leipzig, "Sachsen")
+	data.setCityState(halle, "Sachsen-Anhalt")
+	genuss := data.getOrAddTag("Genuss")
+	kaffee := data.getOrAddTag("Kaffee")
+	kaffee.Parent = genuss
+	trail := data.getOrAddTag("Trail")
+	free := costValues[0]
+
+	a := &Club{Name: "A", City: leipzig, Tags: []*Tag{kaffee}, Runs: []Run{{time.Tuesday, ""}, {time.Sunday, ""}}, Attributes: ClubAttributes{Cost: &free, Paces: []PaceRange{{270, 420}}}}
+	b := &Club{Name: "B", City: leipzig, Tags: []*Tag{trail, kaffee}, Runs: []Run{{time.Tuesday, ""}}}
+	c := &Club{Name: "C", City: halle, Tags: []*Tag{trail}, Attributes: ClubAttributes{Cost: &free}}
+
+	if facets := a.Facets(); facets != "tag:kaffee tag:genuss day:dienstag day:sonntag cost:free pace:schnell pace:gemuetlich state:sachsen city:leipzig" {
+		t.Errorf("Facets() = %q", facets)
+	}
+
+	facets := buildFacets([]*Club{a, b, c})
+	expected := map[string]string{
+		"tag":   "genuss:2 kaffee:2 trail:2",
+		"day":   "dienstag:2 sonntag:1",
+		"cost":  "free:2",
+		"pace":  "schnell:1 gemuetlich:1",
+		"state": "sachsen:2 sachsen-anhalt:1",
+		"city":  "leipzig:2 halle:1",
+	}
+	if len(facets) != len(expected) {
+		t.Fatalf("buildFacets() = %v", facets)
+	}
+	for _, facet := range facets {
+		options := ""
+		for i, option := range facet.Options {
+			if i > 0 {
+				options += " "
+			}
+			options += fmt.Sprintf("%s:%d", option.Value, option.Count)
+		}
+		if options != expected[facet.Key] {
+			t.Errorf("facet %s = %q, want %q", facet.Key, options, expected[facet.Key])
+		}
+	}
+
+	// a facet with one option for all clubs is no choice
+	leipzig.Clubs = []*Club{a, b}
+	if len(leipzig.ClubFacets()) != 4 { // tag, day, cost, pace
+		t.Errorf("ClubFacets() = %v", leipzig.ClubFacets())
+	}
+	for _, facet := range leipzig.ClubFacets() {
+		if facet.Key == "city" || facet.Key == "state" {
+			t.Errorf("city page has facet %s", facet.Key)
+		}
+	}
+}
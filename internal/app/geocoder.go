@@ -0,0 +1,91 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// LoadGeocoderCache loads the geocoder cache configured in config.
+func LoadGeocoderCache(config Config) (*utils.GeocoderCache, error) {
+	var maxAge time.Duration
+	if config.Geocoding.MaxAge != "" {
+		var err error
+		if maxAge, err = time.ParseDuration(config.Geocoding.MaxAge); err != nil {
+			return nil, fmt.Errorf("invalid geocoder max age: %w", err)
+		}
+	}
+	return utils.LoadGeocoderCache(filepath.Join(config.CacheDir, "geocoder.json"), maxAge)
+}
+
+// LoadGazetteer loads the municipality list configured in config.
+func LoadGazetteer(config Config) (utils.Gazetteer, error) {
+	fileName := config.Geocoding.Gazetteer
+	if fileName == "" {
+		fileName = "data/gemeinden.csv"
+	}
+	gazetteer, err := utils.LoadGazetteer(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("loading gazetteer %s: %w", fileName, err)
+	}
+	return gazetteer, nil
+}
+
+// NewGeocoder creates the cached geocoder chains configured in config.
+// Manually entered coordinates (CITIES sheet) are not part of the chains, as
+// they are already set when reading the data. Reverse lookups try Nominatim
+// first by default: the gazetteer only knows the nearest municipality, which
+// may lie in another state.
+func NewGeocoder(config Config) (utils.Geocoder, error) {
+	providers := config.Geocoding.Providers
+	if len(providers) == 0 {
+		providers = []string{"gazetteer", "nominatim"}
+	}
+	reverseProviders := config.Geocoding.ReverseProviders
+	if len(reverseProviders) == 0 {
+		reverseProviders = []string{"nominatim", "gazetteer"}
+	}
+
+	var gazetteer utils.Gazetteer
+	var nominatim *utils.Nominatim
+	newChain := func(providers []string) (utils.GeocoderChain, error) {
+		chain := make(utils.GeocoderChain, 0, len(providers))
+		for _, provider := range providers {
+			switch provider {
+			case "gazetteer":
+				if gazetteer == nil {
+					var err error
+					if gazetteer, err = LoadGazetteer(config); err != nil {
+						return nil, err
+					}
+				}
+				chain = append(chain, gazetteer)
+			case "nominatim":
+				// shared, so that both chains respect the rate limit
+				if nominatim == nil {
+					nominatim = utils.NewNominatim(config.Geocoding.Nominatim)
+				}
+				chain = append(chain, nominatim)
+			default:
+				return nil, fmt.Errorf("unknown geocoder: %q", provider)
+			}
+		}
+		return chain, nil
+	}
+	chain, err := newChain(providers)
+	if err != nil {
+		return nil, err
+	}
+	reverseChain, err := newChain(reverseProviders)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := LoadGeocoderCache(config)
+	if err != nil {
+		return nil, fmt.Errorf("loading geocoder cache: %w", err)
+	}
+	return utils.NewCachingGeocoder(cache, utils.SplitGeocoder{LookupGeocoder: chain, ReverseGeocoder: reverseChain}), nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestNewGeocoderUnknownProvider(t *testing.T) {
+	config := Config{}
+	config.Geocoding.Providers = []string{"gazetteer", "atlas"}
+	config.Geocoding.Gazetteer = "../../data/gemeinden.csv"
+	if _, err := NewGeocoder(config); err == nil {
+		t.Error("NewGeocoder() with unknown provider: expected error")
+	}
+}
+
+func TestNewGeocoderUnknownReverseProvider(t *testing.T) {
+	config := Config{}
+	config.Geocoding.Providers = []string{"gazetteer"}
+	config.Geocoding.ReverseProviders = []string{"atlas"}
+	config.Geocoding.Gazetteer = "../../data/gemeinden.csv"
+	if _, err := NewGeocoder(config); err == nil {
+		t.Error("NewGeocoder() with unknown reverse provider: expected error")
+	}
+}
+
+func TestAnnotateCityCoordinates(t *testing.T) {
+	config := Config{CacheDir: t.TempDir()}
+	config.Geocoding.Providers = []string{"gazetteer"}
+	config.Geocoding.Gazetteer = "../../data/gemeinden.csv"
+	geocoder, err := NewGeocoder(config)
+	if err != nil {
+		t.Fatalf("NewGeocoder() error = %v", err)
+	}
+
+	manual := utils.LatLon{Lat: 52.5, Lon: 13.4}
+	data := &Data{Cities: []*City{
+		{Name: "Berlin", LatLon: &manual},
+		{Name: "Köln"},
+		{Name: "Atlantis"},
+	}}
+	if err := AnnotateCityCoordinates(data, geocoder); err != nil {
+		t.Fatalf("AnnotateCityCoordinates() error = %v", err)
+	}
+
+	if *data.Cities[0].LatLon != manual {
+		t.Errorf("manual coordinates were overwritten: %+v", *data.Cities[0].LatLon)
+	}
+	if data.Cities[1].LatLon == nil {
+		t.Error("Köln: missing coordinates")
+	}
+	if data.Cities[2].LatLon != nil {
+		t.Errorf("Atlantis: unexpected coordinates %+v", *data.Cities[2].LatLon)
+	}
+	if len(data.Findings) != 1 || data.Findings[0].Subject != "Atlantis" || !strings.Contains(data.Findings[0].Message, "gazetteer") {
+		t.Errorf("Findings = %v", data.Findings)
+	}
+
+	// the result is cached with its provenance
+	cache, err := LoadGeocoderCache(config)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
+	}
+	entries := cache.Entries()
+	if len(entries) != 1 || entries[0].Query != "Köln" || entries[0].Provider != "gazetteer" || entries[0].Confidence != 1 {
+		t.Errorf("Entries() = %+v", entries)
+	}
+}
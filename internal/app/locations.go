@@ -0,0 +1,196 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// ClubLocation is a further location of a club, e.g. of a franchise in
+// another city or a second meeting point; the club's main location is given
+// by the club's own fields (City, LatLon, Neighbourhood, MeetingPoint, Runs).
+type ClubLocation struct {
+	Club          *Club
+	City          *City
+	LatLon        *utils.LatLon
+	Neighbourhood string
+	MeetingPoint  string
+	Runs          []Run // own schedule of the location, Monday first
+	clubRaw       string
+	cityRaw       string
+}
+
+// Position returns the coordinates of the location, or of its city if unknown.
+func (l *ClubLocation) Position() *utils.LatLon {
+	if l.LatLon != nil {
+		return l.LatLon
+	}
+	return l.City.LatLon
+}
+
+// clubPosition is a position of a club in the spatial index: the club's main
+// location, or one of its further locations.
+type clubPosition struct {
+	Club     *Club
+	Location *ClubLocation // nil for the club's main location
+}
+
+func (p clubPosition) position() *utils.LatLon {
+	if p.Location != nil {
+		return p.Location.Position()
+	}
+	return p.Club.Location()
+}
+
+// newClubIndex builds a spatial index of the main and further locations of
+// the clubs.
+func newClubIndex(clubs []*Club) *utils.SpatialIndex[clubPosition] {
+	positions := make([]clubPosition, 0, len(clubs))
+	for _, club := range clubs {
+		positions = append(positions, clubPosition{Club: club})
+		for _, location := range club.Locations {
+			positions = append(positions, clubPosition{Club: club, Location: location})
+		}
+	}
+	return utils.NewSpatialIndex(positions, clubPosition.position)
+}
+
+// uniqueClubs reduces neighbours sorted by distance to the nearest position of
+// each club.
+func uniqueClubs(neighbours []utils.Neighbour[clubPosition]) []utils.Neighbour[*Club] {
+	clubs := make([]utils.Neighbour[*Club], 0, len(neighbours))
+	seen := make(map[*Club]bool)
+	for _, n := range neighbours {
+		if !seen[n.Item.Club] {
+			seen[n.Item.Club] = true
+			clubs = append(clubs, utils.Neighbour[*Club]{Item: n.Item.Club, Distance: n.Distance})
+		}
+	}
+	return clubs
+}
+
+// LocationCities returns the cities of the club's further locations other
+// than the club's city, without duplicates.
+func (c *Club) LocationCities() []*City {
+	cities := make([]*City, 0)
+	seen := map[*City]bool{c.City: true}
+	for _, location := range c.Locations {
+		if !seen[location.City] {
+			seen[location.City] = true
+			cities = append(cities, location.City)
+		}
+	}
+	return cities
+}
+
+func processLocationsSheet(sheetName string, rows [][]string, data *Data) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("sheet is empty")
+	}
+
+	required := []string{"CLUB", "CITY"}
+	optional := []string{"COORDS", "NEIGHBOURHOOD", "MEETING_POINT", "WEEKDAYS", "START_TIME"}
+	colIdx, err := extractHeader(rows, required, optional)
+	if err != nil {
+		return err
+	}
+
+	for index, row := range rows[1:] {
+		location := &ClubLocation{}
+		latLonRaw := ""
+		weekdaysRaw := ""
+		startTimeRaw := ""
+
+		if location.clubRaw, err = getVal("CLUB", row, colIdx); err != nil {
+			return fmt.Errorf("row %d: %v", index+2, err)
+		}
+		if location.cityRaw, err = getVal("CITY", row, colIdx); err != nil {
+			return fmt.Errorf("row %d: %v", index+2, err)
+		}
+		optionalMappings := []struct {
+			field *string
+			col   string
+		}{
+			{&latLonRaw, "COORDS"},
+			{&location.Neighbourhood, "NEIGHBOURHOOD"},
+			{&location.MeetingPoint, "MEETING_POINT"},
+			{&weekdaysRaw, "WEEKDAYS"},
+			{&startTimeRaw, "START_TIME"},
+		}
+		for _, mapping := range optionalMappings {
+			*mapping.field = getOptionalVal(mapping.col, row, colIdx)
+		}
+
+		if location.clubRaw == "" {
+			continue
+		}
+		if location.cityRaw == "" {
+			data.addFinding(location.clubRaw, "LOCATIONS row %d: empty city", index+2)
+			continue
+		}
+		if latLonRaw != "" {
+			latlon, err := utils.ParseLatLon(latLonRaw)
+			if err != nil {
+				data.addFinding(location.clubRaw, "LOCATIONS row %d: invalid coords: %q", index+2, latLonRaw)
+			} else {
+				location.LatLon = &latlon
+			}
+		}
+		if location.Runs, err = parseRuns(weekdaysRaw, startTimeRaw); err != nil {
+			data.addFinding(location.clubRaw, "LOCATIONS row %d: %v", index+2, err)
+		}
+		data.locations = append(data.locations, location)
+	}
+
+	return nil
+}
+
+// resolveLocations links the locations of the LOCATIONS sheet to their clubs
+// and cities. Clubs with a location in another city are added to the city's
+// OtherClubs, so they appear on the city page, but keep their page in the
+// club's city.
+func resolveLocations(data *Data) {
+	for _, location := range data.locations {
+		club, err := data.findClub(location.clubRaw)
+		if err != nil {
+			data.addFinding(location.clubRaw, "LOCATIONS: %v", err)
+			continue
+		}
+		city, found := data.CityMap[location.cityRaw]
+		if !found {
+			data.addFinding(club.Name, "LOCATIONS: unknown city %q", location.cityRaw)
+			continue
+		}
+		location.Club = club
+		location.City = city
+		club.Locations = append(club.Locations, location)
+	}
+	data.locations = nil
+
+	for _, club := range data.Clubs {
+		for _, city := range club.LocationCities() {
+			city.OtherClubs = append(city.OtherClubs, club)
+		}
+	}
+	for _, city := range data.Cities {
+		sort.SliceStable(city.OtherClubs, func(i, j int) bool {
+			return city.OtherClubs[i].SanitizeName() < city.OtherClubs[j].SanitizeName()
+		})
+	}
+}
+
+// HasClubs reports whether the city has clubs of its own or clubs of other
+// cities with a location in the city.
+func (c *City) HasClubs() bool {
+	return len(c.Clubs) > 0 || len(c.OtherClubs) > 0
+}
+
+// AllClubs returns the clubs of the city and the clubs of other cities with
+// a location in the city.
+func (c *City) AllClubs() []*Club {
+	if len(c.OtherClubs) == 0 {
+		return c.Clubs
+	}
+	return append(append(make([]*Club, 0, len(c.Clubs)+len(c.OtherClubs)), c.Clubs...), c.OtherClubs...)
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestProcessLocationsSheet(t *testing.T) {
+	data := &Data{}
+	rows := [][]string{
+		{"CLUB", "CITY", "COORDS", "NEIGHBOURHOOD", "MEETING_POINT", "WEEKDAYS", "START_TIME"},
+		{"A", "Halle", "51.48, 11.97", "", "Marktplatz", "Sa", "9:30"},
+		{"A", "", "", "", "", "", ""},
+		{"A", "Halle", "nowhere", "", "", "Funtag", ""},
+		{"", "", "", "", "", "", ""},
+	}
+	if err := processLocationsSheet("LOCATIONS", rows, data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.locations) != 2 || len(data.Findings) != 3 {
+		t.Fatalf("locations = %v, findings = %v", data.locations, data.Findings)
+	}
+	location := data.locations[0]
+	if location.LatLon == nil || location.MeetingPoint != "Marktplatz" || len(location.Runs) != 1 || location.Runs[0] != (Run{time.Saturday, "09:30"}) {
+		t.Errorf("location = %+v", location)
+	}
+
+	// all columns but CLUB and CITY are optional
+	data = &Data{}
+	if err := processLocationsSheet("LOCATIONS", [][]string{{"CLUB", "CITY"}, {"A", "Halle"}}, data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.locations) != 1 || len(data.Findings) != 0 {
+		t.Errorf("locations = %v, findings = %v", data.locations, data.Findings)
+	}
+}
+
+func TestResolveLocations(t *testing.T) {
+	leipzig := &City{Name: "Leipzig"}
+	halle := &City{Name: "Halle"}
+	a := &Club{Name: "A", City: leipzig, Runs: []Run{{time.Tuesday, "19:00"}}, MeetingPoint: "Augustusplatz"}
+	b := &Club{Name: "B", City: halle}
+	leipzig.Clubs = []*Club{a}
+	halle.Clubs = []*Club{b}
+	data := &Data{
+		Cities:  []*City{leipzig, halle},
+		CityMap: map[string]*City{"Leipzig": leipzig, "Halle": halle},
+		Clubs:   []*Club{a, b},
+		locations: []*ClubLocation{
+			{clubRaw: "A", cityRaw: "Halle", MeetingPoint: "Marktplatz", Runs: []Run{{time.Saturday, "09:30"}}},
+			{clubRaw: "Leipzig/A", cityRaw: "Leipzig", Neighbourhood: "Plagwitz", Runs: []Run{{time.Tuesday, "18:00"}}},
+			{clubRaw: "A", cityRaw: "Atlantis"},
+			{clubRaw: "C", cityRaw: "Halle"},
+		},
+	}
+
+	resolveLocations(data)
+	collectWeekdayRuns(data)
+
+	if len(a.Locations) != 2 || len(data.Findings) != 2 {
+		t.Fatalf("locations = %v, findings = %v", a.Locations, data.Findings)
+	}
+	if cities := a.LocationCities(); len(cities) != 1 || cities[0] != halle {
+		t.Errorf("LocationCities() = %v", cities)
+	}
+	if len(halle.OtherClubs) != 1 || halle.OtherClubs[0] != a || len(leipzig.OtherClubs) != 0 {
+		t.Errorf("OtherClubs = %v, %v", halle.OtherClubs, leipzig.OtherClubs)
+	}
+	if clubs := halle.AllClubs(); len(clubs) != 2 || len(halle.Clubs) != 1 {
+		t.Errorf("AllClubs() = %v", clubs)
+	}
+	if weekdays := a.Weekdays(); len(weekdays) != 2 || weekdays[1] != time.Saturday {
+		t.Errorf("Weekdays() = %v", weekdays)
+	}
+
+	// runs of the locations count for the location's city
+	if len(halle.Weekdays) != 1 || halle.Weekdays[0].Weekday != time.Saturday {
+		t.Fatalf("Halle weekdays = %v", halle.Weekdays)
+	}
+	run := halle.Weekdays[0].Runs[0]
+	if run.Club != a || run.City() != halle || run.MeetingPoint() != "Marktplatz" {
+		t.Errorf("Halle run = %+v", run)
+	}
+	tuesday := leipzig.Weekdays[0].Runs
+	if len(tuesday) != 2 || tuesday[0].MeetingPoint() != "Plagwitz" || tuesday[1].MeetingPoint() != "Augustusplatz" {
+		t.Errorf("Leipzig Tuesday runs = %+v", tuesday)
+	}
+	if saturday := data.Weekdays[5]; len(saturday.Runs) != 1 || saturday.Runs[0].City() != halle {
+		t.Errorf("Saturday runs = %+v", saturday.Runs)
+	}
+}
+
+func TestCityWithOtherClubsOnly(t *testing.T) {
+	leipzig := &City{Name: "Leipzig", LatLon: &utils.LatLon{Lat: 51.34, Lon: 12.37}}
+	merseburg := &City{Name: "Merseburg", LatLon: &utils.LatLon{Lat: 51.36, Lon: 11.99}, Population: 33000}
+	data := &Data{Cities: []*City{leipzig, merseburg}, CityMap: map[string]*City{"Leipzig": leipzig, "Merseburg": merseburg}}
+	data.setCityState(leipzig, "Sachsen")
+	data.setCityState(merseburg, "Sachsen-Anhalt")
+	trail := data.getOrAddTag("Trail")
+	a := &Club{Name: "A", City: leipzig, Tags: []*Tag{trail}}
+	b := &Club{Name: "B", City: leipzig}
+	leipzig.Clubs = []*Club{a, b}
+	trail.Clubs = []*Club{a}
+	data.Clubs = []*Club{a, b}
+	data.locations = []*ClubLocation{{clubRaw: "A", cityRaw: "Merseburg"}, {clubRaw: "B", cityRaw: "Merseburg"}}
+	resolveLocations(data)
+
+	if !merseburg.HasClubs() {
+		t.Fatal("HasClubs() = false")
+	}
+	if summary := citySummary(merseburg, nil); !strings.HasPrefix(summary, "In Merseburg gibt es 2 Social Run Clubs") {
+		t.Errorf("citySummary() = %q", summary)
+	}
+	if desc := merseburg.MetaDescription(); !strings.Contains(desc, "2 Einträge: A, B") {
+		t.Errorf("MetaDescription() = %q", desc)
+	}
+	state := data.StateMap["Sachsen-Anhalt"]
+	if len(state.CitiesWithoutClubs()) != 0 || len(state.CitiesWithClubs()) != 1 || state.NumberOfClubs() != 2 {
+		t.Errorf("state: without = %v, with = %v, clubs = %d", state.CitiesWithoutClubs(), state.CitiesWithClubs(), state.NumberOfClubs())
+	}
+	merseburg.SizeIndexWithoutClub = 0
+	rankCitiesWithoutClub(data.Cities)
+	if merseburg.SizeIndexWithoutClub != 0 {
+		t.Errorf("SizeIndexWithoutClub = %d", merseburg.SizeIndexWithoutClub)
+	}
+	index := buildSearchIndex(data, func(p string) string { return p })
+	for _, entry := range index.Entries {
+		if entry.Name == "Merseburg" && entry.Detail != "Sachsen-Anhalt, 2 Clubs" {
+			t.Errorf("search entry = %+v", entry)
+		}
+	}
+
+	var config Config
+	config.CityTags.MinClubs = 1
+	if err := AnnotateCityTags(data, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(merseburg.TagPages) != 1 || merseburg.TagPages[0].Tag != trail {
+		t.Errorf("TagPages = %v", merseburg.TagPages)
+	}
+	config.NearbyClubs.RadiusKM = 50
+	if err := AnnotateNearbyClubs(data, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(merseburg.NearbyClubs) != 0 {
+		t.Errorf("NearbyClubs = %v", merseburg.NearbyClubs)
+	}
+}
+
+func TestClubIndexWithLocations(t *testing.T) {
+	berlin := &City{Name: "Berlin", LatLon: &utils.LatLon{Lat: 52.52, Lon: 13.405}}
+	potsdam := &City{Name: "Potsdam", LatLon: &utils.LatLon{Lat: 52.39, Lon: 13.065}}
+	werder := &City{Name: "Werder (Havel)", LatLon: &utils.LatLon{Lat: 52.38, Lon: 12.94}, Population: 27000}
+	a := &Club{Name: "A", City: berlin}
+	berlin.Clubs = []*Club{a}
+	data := &Data{Cities: []*City{berlin, potsdam, werder}, CityMap: map[string]*City{"Berlin": berlin, "Potsdam": potsdam, "Werder (Havel)": werder}, Clubs: []*Club{a}}
+	data.locations = []*ClubLocation{{clubRaw: "A", cityRaw: "Potsdam", LatLon: &utils.LatLon{Lat: 52.40, Lon: 13.05}}, {clubRaw: "A", cityRaw: "Potsdam"}}
+	resolveLocations(data)
+
+	var config Config
+	config.NearbyClubs.RadiusKM = 15
+	if err := AnnotateNearbyClubs(data, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(werder.NearbyClubs) != 1 || werder.NearbyClubs[0].Club != a || werder.NearbyClubs[0].Distance > 10 {
+		t.Errorf("NearbyClubs = %v", werder.NearbyClubs)
+	}
+
+	config.Coverage.MinPopulation = 10000
+	config.Coverage.RadiusKM = 5
+	report := AnalyzeCoverage(data, utils.Gazetteer{}, config)
+	if len(report.Gaps) != 1 || report.Gaps[0].NearestClub != a || report.Gaps[0].NearestClubDistance > 10 {
+		t.Errorf("Gaps = %+v", report.Gaps)
+	}
+}
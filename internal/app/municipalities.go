@@ -0,0 +1,221 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// maxMunicipalityDistanceKM is the max. distance between a city's coordinates
+// and a municipality of the same name to consider them equal.
+const maxMunicipalityDistanceKM = 10
+
+var (
+	reAGS      = regexp.MustCompile(`^\d{8}$`)
+	reWKTPoint = regexp.MustCompile(`^Point\(\s*(\S+)\s+(\S+)\s*\)$`)
+)
+
+// ImportMunicipalities reads a CSV extract of German municipalities, e.g. the
+// result of scripts/gemeinden.sparql (Wikidata) or a converted Destatis list.
+// Recognized columns: name (or itemLabel), ags, coord (WKT point) or lat/lon,
+// population, area (km²), state (or stateLabel), district (or districtLabel).
+// Rows without name, valid AGS or coordinates and duplicate AGS are skipped.
+func ImportMunicipalities(r io.Reader) (entries []utils.GazetteerEntry, skipped int, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading header: %w", err)
+	}
+	aliases := map[string]string{"itemlabel": "name", "statelabel": "state", "districtlabel": "district"}
+	colIdx := make(map[string]int)
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+		if alias, found := aliases[col]; found {
+			col = alias
+		}
+		colIdx[col] = i
+	}
+	for _, col := range []string{"name", "ags"} {
+		if _, found := colIdx[col]; !found {
+			return nil, 0, fmt.Errorf("missing column: %s", col)
+		}
+	}
+	_, hasCoord := colIdx["coord"]
+	_, hasLat := colIdx["lat"]
+	_, hasLon := colIdx["lon"]
+	if !hasCoord && !(hasLat && hasLon) {
+		return nil, 0, fmt.Errorf("missing column: coord or lat/lon")
+	}
+
+	seen := make(map[string]bool)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		get := func(col string) string {
+			if i, found := colIdx[col]; found && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		entry := utils.GazetteerEntry{Name: get("name"), AGS: get("ags")}
+		latLon, ok := parseMunicipalityLocation(get("coord"), get("lat"), get("lon"))
+		if entry.Name == "" || !reAGS.MatchString(entry.AGS) || !ok || seen[entry.AGS] {
+			skipped++
+			continue
+		}
+		seen[entry.AGS] = true
+		entry.LatLon = latLon
+		if entry.State = get("state"); entry.State == "" {
+			entry.State = StateByAGS(entry.AGS)
+		}
+		entry.District = get("district")
+		if population, err := strconv.ParseFloat(get("population"), 64); err == nil {
+			entry.Population = int(population)
+		}
+		if area, err := strconv.ParseFloat(get("area"), 64); err == nil {
+			entry.Area = area
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].AGS < entries[j].AGS
+	})
+	return entries, skipped, nil
+}
+
+func parseMunicipalityLocation(coord, lat, lon string) (utils.LatLon, bool) {
+	if coord != "" {
+		matches := reWKTPoint.FindStringSubmatch(coord)
+		if matches == nil {
+			return utils.LatLon{}, false
+		}
+		// WKT points are "Point(lon lat)"
+		lon, lat = matches[1], matches[2]
+	}
+	latValue, err1 := strconv.ParseFloat(lat, 64)
+	lonValue, err2 := strconv.ParseFloat(lon, 64)
+	if err1 != nil || err2 != nil {
+		return utils.LatLon{}, false
+	}
+	return utils.LatLon{Lat: latValue, Lon: lonValue}, true
+}
+
+// findMunicipality joins the city with the gazetteer: by the AGS entered in the
+// CITIES sheet, or by name; ambiguous names are resolved by the city's state
+// and coordinates.
+func findMunicipality(city *City, gazetteer utils.Gazetteer, byAGS map[string]utils.GazetteerEntry) (utils.GazetteerEntry, error) {
+	if city.AGS != "" {
+		if entry, found := byAGS[city.AGS]; found {
+			return entry, nil
+		}
+		return utils.GazetteerEntry{}, fmt.Errorf("unknown AGS: %q", city.AGS)
+	}
+
+	candidates := gazetteer.Entries(city.Name)
+	if len(candidates) == 0 {
+		return utils.GazetteerEntry{}, fmt.Errorf("no municipality named %q in dataset", city.Name)
+	}
+	matches := make([]utils.GazetteerEntry, 0, len(candidates))
+	for _, entry := range candidates {
+		if city.State != nil && entry.State != "" && entry.State != city.State.Name {
+			continue
+		}
+		if city.LatLon != nil && utils.Distance(*city.LatLon, entry.LatLon) > maxMunicipalityDistanceKM {
+			continue
+		}
+		matches = append(matches, entry)
+	}
+	switch len(matches) {
+	case 0:
+		return utils.GazetteerEntry{}, fmt.Errorf("no municipality named %q matches state and coordinates", city.Name)
+	case 1:
+		return matches[0], nil
+	}
+	keys := make([]string, 0, len(matches))
+	for _, entry := range matches {
+		keys = append(keys, entry.AGS)
+	}
+	return utils.GazetteerEntry{}, fmt.Errorf("ambiguous name, %d municipalities (AGS %s); please set the AGS column", len(matches), strings.Join(keys, ", "))
+}
+
+// AnnotateCityMetadata enriches the cities with population, AGS, area, state
+// and district from the gazetteer; cities that cannot be matched are recorded
+// as findings. Afterwards, cities without clubs are ranked by population.
+func AnnotateCityMetadata(data *Data, gazetteer utils.Gazetteer) error {
+	byAGS := make(map[string]utils.GazetteerEntry)
+	for _, entries := range gazetteer {
+		for _, entry := range entries {
+			if entry.AGS != "" {
+				byAGS[entry.AGS] = entry
+			}
+		}
+	}
+
+	for _, city := range data.Cities {
+		entry, err := findMunicipality(city, gazetteer, byAGS)
+		if err != nil {
+			data.addFinding(city.Name, "%v", err)
+			continue
+		}
+		city.AGS = entry.AGS
+		city.Area = entry.Area
+		// manually entered values take precedence
+		if city.Population == 0 {
+			city.Population = entry.Population
+		}
+		if city.District == "" {
+			city.District = entry.District
+		}
+		// the AGS determines the state, even if the dataset says otherwise
+		state := StateByAGS(entry.AGS)
+		if state == "" {
+			state = entry.State
+		}
+		if city.State == nil && state != "" {
+			data.setCityState(city, state)
+		}
+	}
+	sortStates(data.States)
+
+	rankCitiesWithoutClub(data.Cities)
+	return nil
+}
+
+// rankCitiesWithoutClub sets SizeIndexWithoutClub of the cities without clubs
+// by decreasing population; cities with unknown population keep their previous
+// order (CITIES sheet) after the others.
+func rankCitiesWithoutClub(cities []*City) {
+	withoutClub := make([]*City, 0)
+	for _, city := range cities {
+		if !city.HasClubs() {
+			withoutClub = append(withoutClub, city)
+		}
+	}
+	sort.SliceStable(withoutClub, func(i, j int) bool {
+		a, b := withoutClub[i], withoutClub[j]
+		if a.Population != b.Population {
+			return a.Population > b.Population
+		}
+		return a.SizeIndexWithoutClub < b.SizeIndexWithoutClub
+	})
+	for i, city := range withoutClub {
+		city.SizeIndexWithoutClub = i + 1
+	}
+}
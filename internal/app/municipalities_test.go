@@ -0,0 +1,104 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestStateByAGS(t *testing.T) {
+	for ags, expected := range map[string]string{
+		"11000000": "Berlin",
+		"09162000": "Bayern",
+		"16051000": "Thüringen",
+		"17000000": "",
+		"1":        "",
+	} {
+		if state := StateByAGS(ags); state != expected {
+			t.Errorf("StateByAGS(%q) = %q, want %q", ags, state, expected)
+		}
+	}
+}
+
+func TestImportMunicipalities(t *testing.T) {
+	extract := "\ufeffitem,itemLabel,ags,coord,population,area,districtLabel\n" +
+		"http://www.wikidata.org/entity/Q64,Berlin,11000000,Point(13.38333 52.51667),3662381,891.12,\n" +
+		"http://www.wikidata.org/entity/Q1,Neustadt,14287260,Point(14.21 51.02),12000,,Landkreis Sächsische Schweiz-Osterzgebirge\n" +
+		"http://www.wikidata.org/entity/Q2,Neustadt,06534017,Point(9.11 50.85),9000,60.1,Landkreis Marburg-Biedenkopf\n" +
+		"http://www.wikidata.org/entity/Q64,Berlin,11000000,Point(13.38333 52.51667),3500000,891.12,\n" +
+		"http://www.wikidata.org/entity/Q3,Nirgendwo,123,Point(1 2),1,,\n" +
+		"http://www.wikidata.org/entity/Q4,Irgendwo,01001000,,1,,\n"
+
+	entries, skipped, err := ImportMunicipalities(strings.NewReader(extract))
+	if err != nil {
+		t.Fatalf("ImportMunicipalities() error = %v", err)
+	}
+	if skipped != 3 {
+		t.Errorf("skipped = %d, want 3", skipped)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	berlin := entries[0]
+	expected := utils.GazetteerEntry{Name: "Berlin", AGS: "11000000", LatLon: utils.LatLon{Lat: 52.51667, Lon: 13.38333}, Region: utils.Region{State: "Berlin"}, Population: 3662381, Area: 891.12}
+	if berlin != expected {
+		t.Errorf("Berlin = %+v, want %+v", berlin, expected)
+	}
+	if entries[1].AGS != "06534017" || entries[1].State != "Hessen" || entries[2].District != "Landkreis Sächsische Schweiz-Osterzgebirge" {
+		t.Errorf("Neustadt = %+v, %+v", entries[1], entries[2])
+	}
+
+	if _, _, err := ImportMunicipalities(strings.NewReader("name,ags\nBerlin,11000000\n")); err == nil {
+		t.Error("ImportMunicipalities() without coordinates: expected error")
+	}
+}
+
+func TestAnnotateCityMetadata(t *testing.T) {
+	gazetteer := utils.Gazetteer{
+		"berlin":   {{Name: "Berlin", AGS: "11000000", LatLon: utils.LatLon{Lat: 52.52, Lon: 13.405}, Region: utils.Region{State: "Berlin"}, Population: 3662000, Area: 891.12}},
+		"neustadt": {{Name: "Neustadt", AGS: "14287260", LatLon: utils.LatLon{Lat: 51.02, Lon: 14.21}, Region: utils.Region{State: "Sachsen"}, Population: 12000}, {Name: "Neustadt", AGS: "06534017", LatLon: utils.LatLon{Lat: 50.85, Lon: 9.11}, Region: utils.Region{State: "Hessen", District: "Marburg-Biedenkopf"}, Population: 9000}},
+		"gotha":    {{Name: "Gotha", AGS: "16067029", LatLon: utils.LatLon{Lat: 50.95, Lon: 10.70}, Region: utils.Region{State: "Thüringen"}, Population: 46000}},
+		"jena":     {{Name: "Jena", AGS: "16053000", LatLon: utils.LatLon{Lat: 50.93, Lon: 11.59}, Region: utils.Region{State: "Thüringen"}, Population: 111000}},
+	}
+
+	berlin := &City{Name: "Berlin", Population: 3700000}
+	berlin.Clubs = []*Club{{Name: "Club", City: berlin}}
+	neustadtHessen := &City{Name: "Neustadt", LatLon: &utils.LatLon{Lat: 50.86, Lon: 9.12}, SizeIndexWithoutClub: 1}
+	neustadtUnknown := &City{Name: "Neustadt", SizeIndexWithoutClub: 2}
+	neustadtByAGS := &City{Name: "Neustadt (Sachsen)", AGS: "14287260", SizeIndexWithoutClub: 3}
+	atlantis := &City{Name: "Atlantis", SizeIndexWithoutClub: 4}
+	gotha := &City{Name: "Gotha", SizeIndexWithoutClub: 5}
+	jena := &City{Name: "Jena", SizeIndexWithoutClub: 6}
+	data := &Data{Cities: []*City{berlin, neustadtHessen, neustadtUnknown, neustadtByAGS, atlantis, gotha, jena}}
+
+	if err := AnnotateCityMetadata(data, gazetteer); err != nil {
+		t.Fatalf("AnnotateCityMetadata() error = %v", err)
+	}
+
+	// manually entered population takes precedence
+	if berlin.AGS != "11000000" || berlin.Population != 3700000 || berlin.Area != 891.12 || berlin.State == nil || berlin.State.Name != "Berlin" {
+		t.Errorf("Berlin = %+v", berlin)
+	}
+	if neustadtHessen.AGS != "06534017" || neustadtHessen.District != "Marburg-Biedenkopf" || neustadtHessen.State.Name != "Hessen" {
+		t.Errorf("Neustadt (Hessen) = %+v", neustadtHessen)
+	}
+	if neustadtByAGS.Population != 12000 || neustadtByAGS.State.Name != "Sachsen" {
+		t.Errorf("Neustadt (Sachsen) = %+v", neustadtByAGS)
+	}
+
+	findings := make(map[string]string)
+	for _, finding := range data.Findings {
+		findings[finding.Subject] = finding.Message
+	}
+	if len(findings) != 2 || !strings.Contains(findings["Neustadt"], "ambiguous") || !strings.Contains(findings["Atlantis"], "no municipality") {
+		t.Errorf("Findings = %v", data.Findings)
+	}
+
+	// cities without clubs are ranked by population, unknown populations last
+	for city, expected := range map[*City]int{jena: 1, gotha: 2, neustadtByAGS: 3, neustadtHessen: 4, neustadtUnknown: 5, atlantis: 6} {
+		if city.SizeIndexWithoutClub != expected {
+			t.Errorf("%s: SizeIndexWithoutClub = %d, want %d", city.Name, city.SizeIndexWithoutClub, expected)
+		}
+	}
+}
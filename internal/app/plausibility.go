@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+const (
+	defaultBoundaryFile          = "data/germany.geojson"
+	defaultMaxClubCityDistanceKM = 50
+)
+
+// CheckClubCoordinates records findings for club meeting points outside of
+// Germany or too far away from the club's city; if swapping latitude and
+// longitude fixes the problem, the swapped coordinates are suggested. Without
+// the outline of Germany, only the distance to the city is checked.
+func CheckClubCoordinates(data *Data, config Config) error {
+	fileName := config.Plausibility.Boundary
+	if fileName == "" {
+		fileName = defaultBoundaryFile
+	}
+	var boundary []utils.Area
+	if config.Plausibility.Boundary == "" && !utils.FileExists(fileName) {
+		log.Printf("plausibility: no outline of Germany (%s), skipping the check for meeting points outside of Germany", fileName)
+	} else {
+		var err error
+		if boundary, err = utils.LoadAreas(fileName, "name"); err != nil {
+			return fmt.Errorf("loading boundary %s: %w", fileName, err)
+		}
+	}
+	maxDistance := config.Plausibility.MaxDistanceKM
+	if maxDistance <= 0 {
+		maxDistance = defaultMaxClubCityDistanceKM
+	}
+
+	for _, club := range data.Clubs {
+		if club.LatLon == nil {
+			continue
+		}
+		if problem := coordinatesProblem(*club.LatLon, club.City, boundary, maxDistance); problem != "" {
+			swapped := utils.LatLon{Lat: club.LatLon.Lon, Lon: club.LatLon.Lat}
+			if coordinatesProblem(swapped, club.City, boundary, maxDistance) == "" {
+				data.addFinding(club.Name, "%s; swapped latitude/longitude? use %.5f, %.5f", problem, swapped.Lat, swapped.Lon)
+			} else {
+				data.addFinding(club.Name, "%s", problem)
+			}
+		}
+	}
+	return nil
+}
+
+// coordinatesProblem describes why the coordinates are implausible for a club
+// of the city, or returns an empty string.
+func coordinatesProblem(coords utils.LatLon, city *City, boundary []utils.Area, maxDistance float64) string {
+	if boundary != nil && utils.FindArea(boundary, coords) == nil {
+		return fmt.Sprintf("meeting point %.5f, %.5f is outside of Germany", coords.Lat, coords.Lon)
+	}
+	if city != nil && city.LatLon != nil {
+		if distance := utils.Distance(coords, *city.LatLon); distance > maxDistance {
+			return fmt.Sprintf("meeting point %.5f, %.5f is %s away from %s", coords.Lat, coords.Lon, utils.FormatDistance(distance), city.Name)
+		}
+	}
+	return ""
+}
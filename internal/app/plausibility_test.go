@@ -0,0 +1,140 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// loadBoundary loads the outline of Germany; the tests are skipped until a
+// real boundary (e.g. NUTS level 0, including the islands) is in place.
+func loadBoundary(t *testing.T) []utils.Area {
+	fileName := filepath.Join("..", "..", defaultBoundaryFile)
+	if !utils.FileExists(fileName) {
+		t.Skipf("no boundary %s", fileName)
+	}
+	boundary, err := utils.LoadAreas(fileName, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return boundary
+}
+
+func TestBoundaryContainsGazetteer(t *testing.T) {
+	boundary := loadBoundary(t)
+	gazetteer, err := utils.LoadGazetteer("../../data/gemeinden.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, entries := range gazetteer {
+		for _, entry := range entries {
+			if utils.FindArea(boundary, entry.LatLon) == nil {
+				t.Errorf("%s (%.4f, %.4f) is outside of the boundary", name, entry.Lat, entry.Lon)
+			}
+		}
+	}
+}
+
+func TestBoundaryBorderTowns(t *testing.T) {
+	boundary := loadBoundary(t)
+	inside := map[string]utils.LatLon{
+		"Kehl":            {Lat: 48.5725, Lon: 7.8157},
+		"Berchtesgaden":   {Lat: 47.6314, Lon: 13.0019},
+		"Bad Reichenhall": {Lat: 47.7247, Lon: 12.8769},
+		"Helgoland":       {Lat: 54.1817, Lon: 7.8867},
+		"Borkum":          {Lat: 53.5872, Lon: 6.6653},
+		"Görlitz":         {Lat: 51.1526, Lon: 14.9877},
+		"Konstanz":        {Lat: 47.6603, Lon: 9.1758},
+	}
+	for name, p := range inside {
+		if utils.FindArea(boundary, p) == nil {
+			t.Errorf("%s %v is outside of the boundary", name, p)
+		}
+	}
+	outside := map[string]utils.LatLon{
+		"Basel":      {Lat: 47.5596, Lon: 7.5886},
+		"Strasbourg": {Lat: 48.5734, Lon: 7.7521},
+		"Salzburg":   {Lat: 47.8095, Lon: 13.0550},
+		"Zgorzelec":  {Lat: 51.1500, Lon: 15.0200},
+		"Paris":      {Lat: 48.86, Lon: 2.35},
+		"Swapped":    {Lat: 13.4, Lon: 52.5},
+	}
+	for name, p := range outside {
+		if utils.FindArea(boundary, p) != nil {
+			t.Errorf("%s %v is inside of the boundary", name, p)
+		}
+	}
+}
+
+func TestCheckClubCoordinates(t *testing.T) {
+	berlin := &City{Name: "Berlin", LatLon: &utils.LatLon{Lat: 52.52, Lon: 13.405}}
+	unknown := &City{Name: "Unknown"}
+	data := &Data{Clubs: []*Club{
+		{Name: "OK", City: berlin, LatLon: &utils.LatLon{Lat: 52.50, Lon: 13.45}},
+		{Name: "No Coords", City: berlin},
+		{Name: "Swapped", City: berlin, LatLon: &utils.LatLon{Lat: 13.45, Lon: 52.50}},
+		{Name: "Paris", City: berlin, LatLon: &utils.LatLon{Lat: 48.86, Lon: 2.35}},
+		{Name: "Munich", City: berlin, LatLon: &utils.LatLon{Lat: 48.14, Lon: 11.58}},
+		{Name: "Munich Unknown City", City: unknown, LatLon: &utils.LatLon{Lat: 48.14, Lon: 11.58}},
+	}}
+
+	// a rough box around Germany is enough to test the checks
+	boundaryFile := filepath.Join(t.TempDir(), "germany.geojson")
+	box := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Deutschland"},"geometry":{"type":"Polygon","coordinates":[[[5.9,47.3],[15.0,47.3],[15.0,55.0],[5.9,55.0],[5.9,47.3]]]}}]}`
+	if err := os.WriteFile(boundaryFile, []byte(box), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var config Config
+	config.Plausibility.Boundary = boundaryFile
+	if err := CheckClubCoordinates(data, config); err != nil {
+		t.Fatalf("CheckClubCoordinates() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"Swapped": "use 52.50000, 13.45000",
+		"Paris":   "outside of Germany",
+		"Munich":  "away from Berlin",
+	}
+	if len(data.Findings) != len(expected) {
+		t.Errorf("Findings = %v", data.Findings)
+	}
+	for _, finding := range data.Findings {
+		if !strings.Contains(finding.Message, expected[finding.Subject]) {
+			t.Errorf("unexpected finding: %v", finding)
+		}
+	}
+	for _, finding := range data.Findings {
+		if finding.Subject == "Paris" && strings.Contains(finding.Message, "swapped") {
+			t.Errorf("unexpected swap suggestion: %v", finding)
+		}
+	}
+}
+
+func TestCheckClubCoordinatesWithoutBoundary(t *testing.T) {
+	berlin := &City{Name: "Berlin", LatLon: &utils.LatLon{Lat: 52.52, Lon: 13.405}}
+	data := &Data{Clubs: []*Club{
+		{Name: "OK", City: berlin, LatLon: &utils.LatLon{Lat: 52.50, Lon: 13.45}},
+		{Name: "Paris", City: berlin, LatLon: &utils.LatLon{Lat: 48.86, Lon: 2.35}},
+	}}
+
+	// the default boundary is optional, a configured one is not
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := CheckClubCoordinates(data, Config{}); err != nil {
+		t.Fatalf("CheckClubCoordinates() error = %v", err)
+	}
+	if len(data.Findings) != 1 || data.Findings[0].Subject != "Paris" || !strings.Contains(data.Findings[0].Message, "away from Berlin") {
+		t.Errorf("Findings = %v", data.Findings)
+	}
+	var config Config
+	config.Plausibility.Boundary = "germany.geojson"
+	if err := CheckClubCoordinates(data, config); err == nil {
+		t.Error("CheckClubCoordinates() with missing boundary: expected error")
+	}
+}
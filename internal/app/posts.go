@@ -0,0 +1,384 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
+)
+
+// postsDir contains the posts as Markdown files with front matter.
+const postsDir = "posts"
+
+const (
+	wordsPerMinute  = 200 // reading speed for the reading time estimate
+	minTOCEntries   = 3   // shorter posts have no table of contents
+	maxRelatedPosts = 3
+)
+
+type Post struct {
+	Title       string
+	Description string
+	Slug        string
+	Published   time.Time // zero if the publication date is unknown
+	Updated     time.Time // zero if not updated since publication
+	Author      string
+	Tags        []string
+	Draft       bool
+	Related     []*Post       // posts sharing tags with this one, most similar first
+	Cover       string        // path of the cover image, set by the renderer
+	Content     template.HTML // set by the renderer
+	TOC         []TOCEntry    // set by the renderer
+	Words       int           // set by the renderer
+	File        string
+	coverFile   string // cover image source file, relative to postsDir
+	body        []byte // Markdown
+}
+
+// PublishedLabel returns the German publication date, e.g. "3. März 2025".
+func (p *Post) PublishedLabel() string {
+	if p.Published.IsZero() {
+		return ""
+	}
+	return utils.FormatDate(p.Published)
+}
+
+// UpdatedLabel returns the German date of the last update, or "" if there is none.
+func (p *Post) UpdatedLabel() string {
+	if p.Updated.IsZero() || !p.Updated.After(p.Published) {
+		return ""
+	}
+	return utils.FormatDate(p.Updated)
+}
+
+// TOCEntry is a heading of a post, linked from the table of contents.
+type TOCEntry struct {
+	Level int // 2 or 3
+	ID    string
+	Title string
+}
+
+// ShowTOC reports whether the post is long enough for a table of contents.
+func (p *Post) ShowTOC() bool {
+	return len(p.TOC) >= minTOCEntries
+}
+
+// ReadingMinutes estimates the reading time of the post, at least one minute.
+func (p *Post) ReadingMinutes() int {
+	return max(1, (p.Words+wordsPerMinute-1)/wordsPerMinute)
+}
+
+// postFrontMatter is the metadata block at the beginning of a post, either
+// YAML (delimited by "---") or TOML (delimited by "+++").
+type postFrontMatter struct {
+	Title       string    `yaml:"title" toml:"title"`
+	Description string    `yaml:"description" toml:"description"`
+	Published   time.Time `yaml:"published" toml:"published"`
+	Updated     time.Time `yaml:"updated" toml:"updated"`
+	Author      string    `yaml:"author" toml:"author"`
+	Tags        []string  `yaml:"tags" toml:"tags"`
+	Cover       string    `yaml:"cover" toml:"cover"`
+	Draft       bool      `yaml:"draft" toml:"draft"`
+}
+
+// splitFrontMatter separates the front matter of a post from its Markdown body.
+func splitFrontMatter(content []byte) (postFrontMatter, []byte, error) {
+	var fm postFrontMatter
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	var delimiter string
+	switch {
+	case bytes.HasPrefix(content, []byte("---\n")):
+		delimiter = "---"
+	case bytes.HasPrefix(content, []byte("+++\n")):
+		delimiter = "+++"
+	default:
+		return fm, nil, fmt.Errorf("missing front matter")
+	}
+	rest := content[len(delimiter)+1:]
+	end := bytes.Index(rest, []byte("\n"+delimiter+"\n"))
+	if end < 0 {
+		if !bytes.HasSuffix(rest, []byte("\n"+delimiter)) {
+			return fm, nil, fmt.Errorf("unterminated front matter")
+		}
+		end = len(rest) - len(delimiter) - 1
+	}
+	header := rest[:end+1]
+	body := rest[min(len(rest), end+len(delimiter)+2):]
+
+	if delimiter == "---" {
+		decoder := yaml.NewDecoder(bytes.NewReader(header))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&fm); err != nil && !errors.Is(err, io.EOF) {
+			return fm, nil, fmt.Errorf("parsing YAML front matter: %w", err)
+		}
+	} else {
+		meta, err := toml.Decode(string(header), &fm)
+		if err != nil {
+			return fm, nil, fmt.Errorf("parsing TOML front matter: %w", err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fm, nil, fmt.Errorf("parsing TOML front matter: unknown field %q", undecoded[0].String())
+		}
+	}
+	return fm, body, nil
+}
+
+// parsePost reads a post file; the slug is derived from the file name.
+func parsePost(file string) (*Post, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	fm, body, err := splitFrontMatter(content)
+	if err != nil {
+		return nil, err
+	}
+	if fm.Title == "" {
+		return nil, fmt.Errorf("no title found")
+	}
+
+	filename := filepath.Base(file)
+	return &Post{
+		Title:       fm.Title,
+		Description: fm.Description,
+		Slug:        "/post/" + strings.TrimSuffix(filename, filepath.Ext(filename)) + "/",
+		Published:   fm.Published,
+		Updated:     fm.Updated,
+		Author:      fm.Author,
+		Tags:        fm.Tags,
+		Draft:       fm.Draft,
+		File:        file,
+		coverFile:   fm.Cover,
+		body:        body,
+	}, nil
+}
+
+// sortPosts orders the posts by publication date, newest first; posts without
+// date come last.
+func sortPosts(posts []*Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		if !posts[i].Published.Equal(posts[j].Published) {
+			return posts[i].Published.After(posts[j].Published)
+		}
+		return posts[i].Title < posts[j].Title
+	})
+}
+
+// collectPosts reads all posts from postsDir; drafts are only included if
+// requested (local builds), but never in production builds.
+func collectPosts(data *Data, includeDrafts bool) error {
+	data.Posts = make([]*Post, 0)
+
+	files, err := filepath.Glob(filepath.Join(postsDir, "*.md"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		post, err := parsePost(file)
+		if err != nil {
+			return fmt.Errorf("post file %s: %w", file, err)
+		}
+		if post.Draft && !includeDrafts {
+			continue
+		}
+		if post.coverFile != "" && !utils.FileExists(filepath.Join(postsDir, post.coverFile)) {
+			return fmt.Errorf("post file %s: missing cover image %s", file, post.coverFile)
+		}
+		data.Posts = append(data.Posts, post)
+	}
+	sortPosts(data.Posts)
+	relatePosts(data.Posts)
+
+	return nil
+}
+
+// relatePosts links each post to the posts with the most tags in common;
+// posts are sorted by date, so more recent posts win ties.
+func relatePosts(posts []*Post) {
+	for _, post := range posts {
+		tags := make(map[string]bool)
+		for _, tag := range post.Tags {
+			tags[strings.ToLower(tag)] = true
+		}
+
+		type candidate struct {
+			post   *Post
+			shared int
+		}
+		candidates := make([]candidate, 0)
+		for _, other := range posts {
+			if other == post || other.Draft {
+				continue
+			}
+			shared := 0
+			for _, tag := range other.Tags {
+				if tags[strings.ToLower(tag)] {
+					shared++
+				}
+			}
+			if shared > 0 {
+				candidates = append(candidates, candidate{other, shared})
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].shared > candidates[j].shared
+		})
+
+		post.Related = nil
+		for i := 0; i < len(candidates) && i < maxRelatedPosts; i++ {
+			post.Related = append(post.Related, candidates[i].post)
+		}
+	}
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	// posts may contain raw HTML, e.g. grids of cards or buttons
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// postFuncs are the functions ("shortcodes") available in post bodies, to
+// embed live data that never goes stale:
+//
+//	{{BasePath "/cities.html"}}  path of a page
+//	{{clubCount}}                number of clubs
+//	{{cityCount}}                number of cities with clubs
+//	{{cityClubs "Berlin"}}       list of the clubs of a city
+//	{{tagClubs "trail"}}         list of the clubs with a tag
+//
+// Unknown cities and tags are errors, so renamed entries break the build
+// instead of silently producing empty lists.
+func postFuncs(tdata TemplateData) texttemplate.FuncMap {
+	basePath := utils.BasePathFunc(tdata)
+	clubList := func(clubs []*Club, withCity bool) string {
+		var buf strings.Builder
+		buf.WriteString("<ul class=\"post-clubs\">\n")
+		for _, club := range clubs {
+			fmt.Fprintf(&buf, "<li><a href=\"%s\">%s</a>", template.HTMLEscapeString(basePath(club.Slug())), template.HTMLEscapeString(club.Name))
+			if withCity {
+				fmt.Fprintf(&buf, " <small>(%s)</small>", template.HTMLEscapeString(club.City.Name))
+			}
+			buf.WriteString("</li>\n")
+		}
+		buf.WriteString("</ul>\n")
+		return buf.String()
+	}
+
+	return texttemplate.FuncMap{
+		"BasePath": basePath,
+		"clubCount": func() int {
+			return tdata.Data.NumberClubs
+		},
+		"cityCount": func() int {
+			count := 0
+			for _, city := range tdata.Data.Cities {
+				if city.HasClubs() {
+					count++
+				}
+			}
+			return count
+		},
+		"cityClubs": func(name string) (string, error) {
+			city, found := tdata.Data.CityMap[name]
+			if !found {
+				return "", fmt.Errorf("unknown city %q", name)
+			}
+			return clubList(city.AllClubs(), false), nil
+		},
+		"tagClubs": func(name string) (string, error) {
+			tag := tdata.Data.lookupTag(name)
+			if tag == nil {
+				return "", fmt.Errorf("unknown tag %q", name)
+			}
+			return clubList(tag.Clubs, true), nil
+		},
+	}
+}
+
+var reHTMLTag = regexp.MustCompile(`<[^>]*>`)
+
+// renderPost converts the post's Markdown body to HTML and collects its table
+// of contents and word count. The body is first executed as a template with
+// the page's data and the postFuncs, e.g. [Städte]({{BasePath "/cities.html"}})
+// or {{.SubmitUrl}}.
+func renderPost(post *Post, tdata TemplateData) error {
+	tmpl, err := texttemplate.New(post.File).Funcs(postFuncs(tdata)).Parse(string(post.body))
+	if err != nil {
+		return err
+	}
+	var expanded bytes.Buffer
+	if err := tmpl.Execute(&expanded, tdata); err != nil {
+		return err
+	}
+	source := expanded.Bytes()
+
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	toc := make([]TOCEntry, 0)
+	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if heading.Level == 2 || heading.Level == 3 {
+			id, _ := heading.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			toc = append(toc, TOCEntry{Level: heading.Level, ID: string(idBytes), Title: headingText(heading, source)})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		return err
+	}
+	post.Content = template.HTML(buf.String())
+	post.TOC = toc
+	post.Words = len(strings.Fields(reHTMLTag.ReplaceAllString(buf.String(), " ")))
+	return nil
+}
+
+// headingText returns the plain text of a heading, without any markup.
+func headingText(heading *ast.Heading, source []byte) string {
+	var buf strings.Builder
+	_ = ast.Walk(heading, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			buf.Write(n.Value(source))
+			if n.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
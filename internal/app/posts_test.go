@@ -0,0 +1,212 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	yamlPost := "---\ntitle: \"Hallo: Welt\"\npublished: 2025-03-03\ntags: [laufen, community]\ndraft: true\n---\n\n# Text\n"
+	fm, body, err := splitFrontMatter([]byte(yamlPost))
+	if err != nil {
+		t.Fatalf("YAML: error = %v", err)
+	}
+	if fm.Title != "Hallo: Welt" || fm.Published.Format("2006-01-02") != "2025-03-03" || len(fm.Tags) != 2 || !fm.Draft || string(body) != "\n# Text\n" {
+		t.Errorf("YAML: %+v, body %q", fm, body)
+	}
+
+	tomlPost := "+++\r\ntitle = \"Hallo\"\r\npublished = 2025-03-04\r\nupdated = 2025-04-01\r\nauthor = \"Anna\"\r\ncover = \"img/cover.jpg\"\r\n+++\r\nText"
+	fm, body, err = splitFrontMatter([]byte(tomlPost))
+	if err != nil {
+		t.Fatalf("TOML: error = %v", err)
+	}
+	if fm.Title != "Hallo" || fm.Published.Format("2006-01-02") != "2025-03-04" || fm.Updated.Format("2006-01-02") != "2025-04-01" || fm.Author != "Anna" || fm.Cover != "img/cover.jpg" || string(body) != "Text" {
+		t.Errorf("TOML: %+v, body %q", fm, body)
+	}
+
+	for name, content := range map[string]string{
+		"missing":       "# Text\n",
+		"unterminated":  "---\ntitle: x\n",
+		"unknown YAML":  "---\ntitel: x\n---\n",
+		"unknown TOML":  "+++\ntitel = \"x\"\n+++\n",
+		"invalid date":  "---\npublished: gestern\n---\n",
+		"invalid TOML":  "+++\ntitle = \n+++\n",
+		"invalid YAML":  "---\ntitle: [\n---\n",
+		"wrong closing": "---\ntitle: x\n+++\n",
+	} {
+		if _, _, err := splitFrontMatter([]byte(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParsePosts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"alt.md":      "---\ntitle: Alt\npublished: 2024-01-01\n---\nText",
+		"neu.md":      "---\ntitle: Neu\npublished: 2025-01-01\nupdated: 2025-02-01\n---\nText",
+		"entwurf.md":  "---\ntitle: Entwurf\ndraft: true\n---\nText",
+		"auch-neu.md": "---\ntitle: Auch neu\npublished: 2025-01-01\n---\nText",
+	}
+	posts := make([]*Post, 0)
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		post, err := parsePost(file)
+		if err != nil {
+			t.Fatalf("parsePost(%s) error = %v", name, err)
+		}
+		posts = append(posts, post)
+	}
+	sortPosts(posts)
+
+	expected := []string{"/post/auch-neu/", "/post/neu/", "/post/alt/", "/post/entwurf/"}
+	for i, slug := range expected {
+		if posts[i].Slug != slug {
+			t.Errorf("posts[%d] = %s, want %s", i, posts[i].Slug, slug)
+		}
+	}
+	if label := posts[1].UpdatedLabel(); label != "1. Februar 2025" {
+		t.Errorf("UpdatedLabel() = %q", label)
+	}
+	if label := posts[0].UpdatedLabel(); label != "" {
+		t.Errorf("UpdatedLabel() without update = %q", label)
+	}
+
+	file := filepath.Join(dir, "undated.md")
+	if err := os.WriteFile(file, []byte("---\ntitle: Ohne Datum\n---\nText"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	undated, err := parsePost(file)
+	if err != nil {
+		t.Fatalf("parsePost() without publication date error = %v", err)
+	}
+	if label := undated.PublishedLabel(); label != "" {
+		t.Errorf("PublishedLabel() without publication date = %q", label)
+	}
+	posts = append([]*Post{undated}, posts...)
+	sortPosts(posts)
+	if posts[len(posts)-1] != undated {
+		t.Errorf("posts[%d] = %s, want %s", len(posts)-1, posts[len(posts)-1].Slug, undated.Slug)
+	}
+}
+
+func TestSitePosts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", postsDir, "*.md"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no posts found: %v", err)
+	}
+	for _, file := range files {
+		post, err := parsePost(file)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if err := renderPost(post, TemplateData{isRemoteTarget: true, SubmitUrl: "https://example.com/submit", Data: &Data{}}); err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if content := post.Content; strings.Contains(string(content), "{{") || strings.Contains(string(content), "<!-- raw HTML omitted -->") {
+			t.Errorf("%s: unprocessed content: %s", file, content)
+		}
+	}
+}
+
+func TestRenderPost(t *testing.T) {
+	post := &Post{File: "test.md", body: []byte("## Los geht's\n\nSiehe [Städte]({{BasePath \"/cities.html\"}}) und [Tags]({{BasePath \"/tag/trail\"}}).\n\n<div class=\"grid\">\n    <article>Karte</article>\n</div>\n\n<a role=\"button\" href=\"{{.SubmitUrl}}\">Eintragen</a>\n\n### Mit *Markup*\n\n#### Zu tief\n")}
+
+	if err := renderPost(post, TemplateData{isRemoteTarget: true, SubmitUrl: "https://example.com/submit"}); err != nil {
+		t.Fatalf("renderPost() error = %v", err)
+	}
+	for _, expected := range []string{
+		`<h2 id="los-gehts">Los geht's</h2>`,
+		`<a href="/cities.html">Städte</a>`,
+		`<a href="/tag/trail/">Tags</a>`,
+		`<div class="grid">`,
+		`href="https://example.com/submit"`,
+	} {
+		if !strings.Contains(string(post.Content), expected) {
+			t.Errorf("content does not contain %q:\n%s", expected, post.Content)
+		}
+	}
+	expectedTOC := []TOCEntry{{2, "los-gehts", "Los geht's"}, {3, "mit-markup", "Mit Markup"}}
+	if len(post.TOC) != len(expectedTOC) || post.TOC[0] != expectedTOC[0] || post.TOC[1] != expectedTOC[1] || post.ShowTOC() {
+		t.Errorf("TOC = %+v", post.TOC)
+	}
+	if post.Words != 13 || post.ReadingMinutes() != 1 {
+		t.Errorf("Words = %d, ReadingMinutes() = %d", post.Words, post.ReadingMinutes())
+	}
+	post.Words = 401
+	if post.ReadingMinutes() != 3 {
+		t.Errorf("ReadingMinutes() = %d, want 3", post.ReadingMinutes())
+	}
+
+	if err := renderPost(post, TemplateData{basePath: "/tmp/out"}); err != nil {
+		t.Fatalf("renderPost() error = %v", err)
+	}
+	if !strings.Contains(string(post.Content), `<a href="/tmp/out/cities.html">`) {
+		t.Errorf("local build: %s", post.Content)
+	}
+
+	post.body = []byte("{{BasePath}")
+	if err := renderPost(post, TemplateData{}); err == nil {
+		t.Error("renderPost() with invalid template: expected error")
+	}
+}
+
+func TestPostShortcodes(t *testing.T) {
+	berlin := &City{Name: "Berlin"}
+	koeln := &City{Name: "Köln"}
+	data := &Data{Cities: []*City{berlin, koeln, {Name: "Leer"}}, CityMap: map[string]*City{"Berlin": berlin, "Köln": koeln}, NumberClubs: 3}
+	brc := &Club{Name: "Berlin <Run> Club", City: berlin}
+	spree := &Club{Name: "Spree Runners", City: berlin}
+	koelnClub := &Club{Name: "Köln Läufer", City: koeln}
+	berlin.Clubs = []*Club{brc, spree}
+	koeln.Clubs = []*Club{koelnClub}
+	trail := data.getOrAddTag("Trail")
+	trail.Clubs = []*Club{spree, koelnClub}
+
+	post := &Post{File: "test.md", body: []byte("{{clubCount}} Clubs in {{cityCount}} Städten.\n\n{{cityClubs \"Berlin\"}}\n{{tagClubs \"trail\"}}\n")}
+	if err := renderPost(post, TemplateData{isRemoteTarget: true, Data: data}); err != nil {
+		t.Fatalf("renderPost() error = %v", err)
+	}
+	for _, expected := range []string{
+		"<p>3 Clubs in 2 Städten.</p>",
+		`<li><a href="/berlin/berlin-run-club/">Berlin &lt;Run&gt; Club</a></li>`,
+		`<li><a href="/koeln/koeln-laeufer/">Köln Läufer</a> <small>(Köln)</small></li>`,
+	} {
+		if !strings.Contains(string(post.Content), expected) {
+			t.Errorf("content does not contain %q:\n%s", expected, post.Content)
+		}
+	}
+
+	for _, body := range []string{`{{cityClubs "Atlantis"}}`, `{{tagClubs "unbekannt"}}`} {
+		post.body = []byte(body)
+		if err := renderPost(post, TemplateData{Data: data}); err == nil {
+			t.Errorf("%s: expected error", body)
+		}
+	}
+}
+
+func TestRelatePosts(t *testing.T) {
+	a := &Post{Slug: "a", Tags: []string{"einstieg", "community"}}
+	b := &Post{Slug: "b", Tags: []string{"Einstieg", "community"}}
+	c := &Post{Slug: "c", Tags: []string{"einstieg"}}
+	d := &Post{Slug: "d", Tags: []string{"training"}}
+	draft := &Post{Slug: "draft", Tags: []string{"einstieg", "community"}, Draft: true}
+	relatePosts([]*Post{a, b, c, d, draft})
+
+	if len(a.Related) != 2 || a.Related[0] != b || a.Related[1] != c {
+		t.Errorf("a.Related = %v", a.Related)
+	}
+	if len(c.Related) != 2 || c.Related[0] != a || c.Related[1] != b {
+		t.Errorf("c.Related = %v", c.Related)
+	}
+	if len(d.Related) != 0 {
+		t.Errorf("d.Related = %v", d.Related)
+	}
+}
@@ -0,0 +1,300 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// StateNames are the German federal states (Bundesländer).
+var StateNames = []string{
+	"Baden-Württemberg",
+	"Bayern",
+	"Berlin",
+	"Brandenburg",
+	"Bremen",
+	"Hamburg",
+	"Hessen",
+	"Mecklenburg-Vorpommern",
+	"Niedersachsen",
+	"Nordrhein-Westfalen",
+	"Rheinland-Pfalz",
+	"Saarland",
+	"Sachsen",
+	"Sachsen-Anhalt",
+	"Schleswig-Holstein",
+	"Thüringen",
+}
+
+// stateCodes maps the first two digits of an AGS to the state.
+var stateCodes = map[string]string{
+	"01": "Schleswig-Holstein",
+	"02": "Hamburg",
+	"03": "Niedersachsen",
+	"04": "Bremen",
+	"05": "Nordrhein-Westfalen",
+	"06": "Hessen",
+	"07": "Rheinland-Pfalz",
+	"08": "Baden-Württemberg",
+	"09": "Bayern",
+	"10": "Saarland",
+	"11": "Berlin",
+	"12": "Brandenburg",
+	"13": "Mecklenburg-Vorpommern",
+	"14": "Sachsen",
+	"15": "Sachsen-Anhalt",
+	"16": "Thüringen",
+}
+
+// StateByAGS returns the state of a municipality given by its official key
+// (Amtlicher Gemeindeschlüssel), or an empty string.
+func StateByAGS(ags string) string {
+	if len(ags) < 2 {
+		return ""
+	}
+	return stateCodes[ags[:2]]
+}
+
+func isStateName(name string) bool {
+	for _, stateName := range StateNames {
+		if name == stateName {
+			return true
+		}
+	}
+	return false
+}
+
+type State struct {
+	Name   string
+	Cities []*City
+}
+
+func (s *State) Slug() string {
+	return fmt.Sprintf("/bundesland/%s", utils.SanitizeName(s.Name))
+}
+
+// Clubs returns the clubs of all cities of the state, including clubs of
+// other states with a location in the state.
+func (s *State) Clubs() []*Club {
+	clubs := make([]*Club, 0)
+	seen := make(map[*Club]bool)
+	for _, city := range s.Cities {
+		for _, club := range city.AllClubs() {
+			if !seen[club] {
+				seen[club] = true
+				clubs = append(clubs, club)
+			}
+		}
+	}
+	sortClubs(clubs)
+	return clubs
+}
+
+func (s *State) NumberOfClubs() int {
+	return len(s.Clubs())
+}
+
+// CitiesWithClubs returns all cities of the state with at least one club.
+func (s *State) CitiesWithClubs() []*City {
+	cities := make([]*City, 0)
+	for _, city := range s.Cities {
+		if city.HasClubs() {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+// CitiesWithoutClubs returns all shown cities of the state without clubs.
+func (s *State) CitiesWithoutClubs() []*City {
+	cities := make([]*City, 0)
+	for _, city := range s.Cities {
+		if !city.HasClubs() && city.Show() {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+func (s *State) MetaDescription() string {
+	numberOfClubs := s.NumberOfClubs()
+	if numberOfClubs == 0 {
+		return fmt.Sprintf("Eine Übersicht über alle Social Run Clubs in %s. Aktuell gibt es leider noch keine Einträge - du kannst aber gerne einen neuen Club hinzufügen!", s.Name)
+	}
+	clubs := fmt.Sprintf("%d Clubs", numberOfClubs)
+	if numberOfClubs == 1 {
+		clubs = "einen Club"
+	}
+	cities := fmt.Sprintf("%d Städten", len(s.CitiesWithClubs()))
+	if len(s.CitiesWithClubs()) == 1 {
+		cities = "einer Stadt"
+	}
+	return fmt.Sprintf("Eine Übersicht über alle Social Run Clubs in %s. Aktuell gibt es %s in %s.", s.Name, clubs, cities)
+}
+
+func (d *Data) getOrAddState(name string) *State {
+	if d.StateMap == nil {
+		d.StateMap = make(map[string]*State)
+	}
+	if state, found := d.StateMap[name]; found {
+		return state
+	}
+	state := &State{Name: name}
+	d.States = append(d.States, state)
+	d.StateMap[name] = state
+	return state
+}
+
+// setCityState assigns the city to the named state; unknown state names are
+// recorded as findings.
+func (d *Data) setCityState(city *City, name string) {
+	if !isStateName(name) {
+		d.addFinding(city.Name, "unknown state: %q", name)
+		return
+	}
+	if city.State != nil {
+		if city.State.Name == name {
+			return
+		}
+		d.addFinding(city.Name, "conflicting states: %q and %q", city.State.Name, name)
+		return
+	}
+	state := d.getOrAddState(name)
+	state.Cities = append(state.Cities, city)
+	city.State = state
+}
+
+func sortStates(states []*State) {
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Slug() < states[j].Slug()
+	})
+	for _, state := range states {
+		sort.Slice(state.Cities, func(i, j int) bool {
+			return state.Cities[i].Slug() < state.Cities[j].Slug()
+		})
+	}
+}
+
+// AnnotateCityRegions determines state and district of all cities without manually
+// entered region, from the AGS (state only) or via reverse geocoding; cities
+// without state are recorded as findings.
+func AnnotateCityRegions(data *Data, geocoder utils.Geocoder) error {
+	for _, city := range data.Cities {
+		if city.State != nil && city.District != "" {
+			continue
+		}
+		if city.State == nil && StateByAGS(city.AGS) != "" {
+			data.setCityState(city, StateByAGS(city.AGS))
+			if city.District != "" {
+				continue
+			}
+		}
+		if city.LatLon == nil {
+			if city.State == nil {
+				data.addFinding(city.Name, "cannot determine state without coordinates")
+			}
+			continue
+		}
+
+		result, err := geocoder.Reverse(*city.LatLon)
+		if err != nil {
+			if city.State == nil {
+				data.addFinding(city.Name, "getting region: %v", err)
+			}
+			continue
+		}
+		if city.District == "" {
+			city.District = result.District
+		}
+		if city.State == nil {
+			data.setCityState(city, result.State)
+		}
+	}
+
+	sortStates(data.States)
+	return nil
+}
+
+// Neighbourhood is a group of clubs of a city.
+type Neighbourhood struct {
+	Name  string // empty for clubs without neighbourhood
+	Clubs []*Club
+}
+
+func (n *Neighbourhood) Anchor() string {
+	if n.Name == "" {
+		return "stadtteil-weitere"
+	}
+	return fmt.Sprintf("stadtteil-%s", utils.SanitizeName(n.Name))
+}
+
+// Neighbourhoods groups the clubs of the city by neighbourhood (sorted by name,
+// clubs without neighbourhood last); nil if no club has a neighbourhood.
+func (c *City) Neighbourhoods() []*Neighbourhood {
+	groups := make(map[string]*Neighbourhood)
+	neighbourhoods := make([]*Neighbourhood, 0)
+	for _, club := range c.Clubs {
+		group, found := groups[club.Neighbourhood]
+		if !found {
+			group = &Neighbourhood{Name: club.Neighbourhood}
+			groups[club.Neighbourhood] = group
+			neighbourhoods = append(neighbourhoods, group)
+		}
+		group.Clubs = append(group.Clubs, club)
+	}
+	if _, found := groups[""]; len(groups) == 0 || (found && len(groups) == 1) {
+		return nil
+	}
+
+	sort.Slice(neighbourhoods, func(i, j int) bool {
+		a, b := neighbourhoods[i].Name, neighbourhoods[j].Name
+		if a == "" || b == "" {
+			return b == ""
+		}
+		return utils.SanitizeName(a) < utils.SanitizeName(b)
+	})
+	return neighbourhoods
+}
+
+// AnnotateClubNeighbourhoods determines the neighbourhood of all clubs (and
+// further club locations) without manually entered neighbourhood by checking
+// their coordinates against the neighbourhood polygons configured for their
+// city.
+func AnnotateClubNeighbourhoods(data *Data, config Config) error {
+	for cityName, fileName := range config.Neighbourhoods {
+		city, found := data.CityMap[cityName]
+		if !found {
+			data.addFinding(cityName, "neighbourhoods configured for unknown city")
+			continue
+		}
+		areas, err := utils.LoadAreas(fileName, "name")
+		if err != nil {
+			return fmt.Errorf("loading neighbourhoods of %s: %w", cityName, err)
+		}
+
+		for _, club := range city.Clubs {
+			if club.Neighbourhood != "" || club.LatLon == nil {
+				continue
+			}
+			if area := utils.FindArea(areas, *club.LatLon); area != nil {
+				club.Neighbourhood = area.Name
+			} else {
+				data.addFinding(club.Name, "location %.5f,%.5f is outside of all neighbourhoods of %s", club.LatLon.Lat, club.LatLon.Lon, cityName)
+			}
+		}
+		for _, club := range city.AllClubs() {
+			for _, location := range club.Locations {
+				if location.City != city || location.Neighbourhood != "" || location.LatLon == nil {
+					continue
+				}
+				if area := utils.FindArea(areas, *location.LatLon); area != nil {
+					location.Neighbourhood = area.Name
+				} else {
+					data.addFinding(club.Name, "location %.5f,%.5f is outside of all neighbourhoods of %s", location.LatLon.Lat, location.LatLon.Lon, cityName)
+				}
+			}
+		}
+	}
+	return nil
+}
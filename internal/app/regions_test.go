@@ -0,0 +1,169 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+func TestAnnotateCityRegions(t *testing.T) {
+	gazetteer := utils.Gazetteer{
+		"freiburg im breisgau": {{LatLon: utils.LatLon{Lat: 47.999, Lon: 7.842}, Region: utils.Region{State: "Baden-Württemberg"}}},
+		"hürth":                {{LatLon: utils.LatLon{Lat: 50.877, Lon: 6.876}, Region: utils.Region{State: "Nordrhein-Westfalen", District: "Rhein-Erft-Kreis"}}},
+	}
+
+	data := &Data{}
+	freiburg := &City{Name: "Freiburg", LatLon: &utils.LatLon{Lat: 47.99, Lon: 7.85}}
+	huerth := &City{Name: "Hürth", LatLon: &utils.LatLon{Lat: 50.88, Lon: 6.87}}
+	berlin := &City{Name: "Berlin"}
+	atlantis := &City{Name: "Atlantis", LatLon: &utils.LatLon{Lat: 54.5, Lon: 3.0}}
+	data.Cities = []*City{freiburg, huerth, berlin, atlantis}
+	data.setCityState(berlin, "Berlin")
+	data.setCityState(berlin, "Brandenburg")
+
+	if err := AnnotateCityRegions(data, gazetteer); err != nil {
+		t.Fatalf("AnnotateCityRegions() error = %v", err)
+	}
+
+	if freiburg.State == nil || freiburg.State.Name != "Baden-Württemberg" {
+		t.Errorf("Freiburg: state = %+v", freiburg.State)
+	}
+	if huerth.State == nil || huerth.State.Name != "Nordrhein-Westfalen" || huerth.District != "Rhein-Erft-Kreis" {
+		t.Errorf("Hürth: state = %+v, district = %q", huerth.State, huerth.District)
+	}
+	if berlin.State == nil || berlin.State.Name != "Berlin" {
+		t.Errorf("Berlin: state = %+v", berlin.State)
+	}
+	if atlantis.State != nil {
+		t.Errorf("Atlantis: state = %+v", atlantis.State)
+	}
+
+	names := make([]string, 0)
+	for _, state := range data.States {
+		names = append(names, state.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Baden-Württemberg", "Berlin", "Nordrhein-Westfalen"}) {
+		t.Errorf("States = %v", names)
+	}
+
+	subjects := make([]string, 0)
+	for _, finding := range data.Findings {
+		subjects = append(subjects, finding.Subject)
+	}
+	if !reflect.DeepEqual(subjects, []string{"Berlin", "Atlantis"}) {
+		t.Errorf("Findings = %v", data.Findings)
+	}
+}
+
+func TestAnnotateCityRegionsFromAGS(t *testing.T) {
+	// Neu-Ulm lies next to Ulm, the nearest municipality in the gazetteer
+	gazetteer := utils.Gazetteer{
+		"ulm": {{Name: "Ulm", AGS: "08421000", LatLon: utils.LatLon{Lat: 48.398, Lon: 9.992}, Region: utils.Region{State: "Baden-Württemberg"}}},
+	}
+	data := &Data{}
+	neuUlm := &City{Name: "Neu-Ulm", AGS: "09775164", LatLon: &utils.LatLon{Lat: 48.392, Lon: 10.011}}
+	data.Cities = []*City{neuUlm}
+	if err := AnnotateCityRegions(data, gazetteer); err != nil {
+		t.Fatalf("AnnotateCityRegions() error = %v", err)
+	}
+	if neuUlm.State == nil || neuUlm.State.Name != "Bayern" {
+		t.Errorf("Neu-Ulm: state = %+v", neuUlm.State)
+	}
+}
+
+func TestSetCityStateUnknown(t *testing.T) {
+	data := &Data{}
+	city := &City{Name: "Musterstadt"}
+	data.setCityState(city, "Bayer")
+	if city.State != nil || len(data.States) != 0 || len(data.Findings) != 1 {
+		t.Errorf("setCityState() with unknown state: state = %+v, findings = %v", city.State, data.Findings)
+	}
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	data := &Data{}
+	munich := &City{Name: "München"}
+	berlin := &City{Name: "Berlin"}
+	data.setCityState(munich, "Bayern")
+	data.setCityState(berlin, "Berlin")
+	club := &Club{Name: "Isar Runners", City: munich}
+
+	crumbs := func(t TemplateData) []string {
+		result := make([]string, 0)
+		for _, b := range t.Breadcrumbs() {
+			result = append(result, b.Path)
+		}
+		return result
+	}
+
+	if got := crumbs(TemplateData{City: munich, Club: club}); !reflect.DeepEqual(got, []string{"/", "/bundesland/bayern", "/muenchen", "/muenchen/isar-runners"}) {
+		t.Errorf("club breadcrumbs = %v", got)
+	}
+	if got := crumbs(TemplateData{City: berlin}); !reflect.DeepEqual(got, []string{"/", "/berlin"}) {
+		t.Errorf("city state breadcrumbs = %v", got)
+	}
+	if got := crumbs(TemplateData{State: munich.State}); !reflect.DeepEqual(got, []string{"/", "/bundesland/bayern"}) {
+		t.Errorf("state breadcrumbs = %v", got)
+	}
+	dachau := &City{Name: "Dachau", District: "Dachau"}
+	data.setCityState(dachau, "Bayern")
+	if place := cityPlace(dachau); place["containedInPlace"].(jsonLD)["name"] != "Dachau" || place["containedInPlace"].(jsonLD)["containedInPlace"].(jsonLD)["name"] != "Bayern" {
+		t.Errorf("cityPlace() = %v", place)
+	}
+	if got := (TemplateData{}).Breadcrumbs(); got != nil {
+		t.Errorf("breadcrumbs without entity = %v", got)
+	}
+	if got := (TemplateData{}).JSONLD(); got != "" {
+		t.Errorf("JSONLD() without entity = %q", got)
+	}
+}
+
+func TestCityNeighbourhoods(t *testing.T) {
+	city := &City{Name: "Berlin"}
+	if city.Neighbourhoods() != nil {
+		t.Error("Neighbourhoods() of city without clubs: expected nil")
+	}
+
+	city.Clubs = []*Club{{Name: "A"}, {Name: "B"}}
+	if city.Neighbourhoods() != nil {
+		t.Error("Neighbourhoods() without neighbourhood data: expected nil")
+	}
+
+	fileName := filepath.Join(t.TempDir(), "berlin.geojson")
+	geojson := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Mitte"},"geometry":{"type":"Polygon","coordinates":[[[13.3,52.5],[13.4,52.5],[13.4,52.6],[13.3,52.6],[13.3,52.5]]]}}]}`
+	if err := os.WriteFile(fileName, []byte(geojson), 0644); err != nil {
+		t.Fatal(err)
+	}
+	city.Clubs = []*Club{
+		{Name: "A", LatLon: &utils.LatLon{Lat: 52.55, Lon: 13.35}},
+		{Name: "B", Neighbourhood: "Wedding", LatLon: &utils.LatLon{Lat: 52.55, Lon: 13.35}},
+		{Name: "C", LatLon: &utils.LatLon{Lat: 52.4, Lon: 13.6}},
+		{Name: "D"},
+		{Name: "E", Neighbourhood: "Kreuzberg"},
+	}
+	data := &Data{Cities: []*City{city}, CityMap: map[string]*City{"Berlin": city}}
+	config := Config{Neighbourhoods: map[string]string{"Berlin": fileName}}
+	if err := AnnotateClubNeighbourhoods(data, config); err != nil {
+		t.Fatalf("AnnotateClubNeighbourhoods() error = %v", err)
+	}
+	if len(data.Findings) != 1 || data.Findings[0].Subject != "C" {
+		t.Errorf("Findings = %v", data.Findings)
+	}
+
+	groups := make([]string, 0)
+	for _, n := range city.Neighbourhoods() {
+		names := make([]string, 0)
+		for _, club := range n.Clubs {
+			names = append(names, club.Name)
+		}
+		groups = append(groups, fmt.Sprintf("%s:%s=%v", n.Anchor(), n.Name, names))
+	}
+	expected := []string{"stadtteil-kreuzberg:Kreuzberg=[E]", "stadtteil-mitte:Mitte=[A]", "stadtteil-wedding:Wedding=[B]", "stadtteil-weitere:=[C D]"}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Neighbourhoods() = %v, want %v", groups, expected)
+	}
+}
@@ -2,11 +2,15 @@ package app
 
 import (
 	"fmt"
+	"image"
+	"log"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/flopp/socialrunclubs-de/internal/images"
 	"github.com/flopp/socialrunclubs-de/internal/utils"
 )
 
@@ -27,7 +31,11 @@ type TemplateData struct {
 	City           *City
 	Club           *Club
 	Tag            *Tag
+	CityTag        *CityTag
+	Weekday        *WeekdayRuns
 	Post           *Post
+	State          *State
+	NoIndex        bool // internal pages that should not be indexed by search engines
 }
 
 func (t TemplateData) IsRemoteTarget() bool {
@@ -151,6 +159,13 @@ func renderStaticPages(data *Data, config Config, cssFiles, otherJS []string, um
 			Template:    "clubs.html",
 			OutFile:     "clubs.html",
 		},
+		{
+			Title:       "Social Run Clubs in den Bundesländern",
+			Description: "Eine Übersicht über alle Social Run Clubs nach Bundesländern.",
+			Canonical:   "/bundeslaender.html",
+			Template:    "states.html",
+			OutFile:     "bundeslaender.html",
+		},
 		{
 			Title:       "Social Run Club Kategorien",
 			Description: "Eine Übersicht über alle Social Run Club Kategorien.",
@@ -158,6 +173,27 @@ func renderStaticPages(data *Data, config Config, cssFiles, otherJS []string, um
 			Template:    "tags.html",
 			OutFile:     "tags.html",
 		},
+		{
+			Title:       "Heute laufen - Social Run Clubs",
+			Description: "Welche Social Run Clubs laufen heute und in den nächsten Tagen? Alle Läufe mit Startzeit und Treffpunkt.",
+			Canonical:   "/heute.html",
+			Template:    "today.html",
+			OutFile:     "heute.html",
+		},
+		{
+			Title:       "Termine - Social Run Clubs",
+			Description: "Kommende Events der Social Run Clubs in Deutschland: Launch-Partys, Charity-Läufe und gemeinsame Fahrten zu Laufveranstaltungen.",
+			Canonical:   "/termine.html",
+			Template:    "events.html",
+			OutFile:     "termine.html",
+		},
+		{
+			Title:       "Suche - Social Run Clubs",
+			Description: "Durchsuche alle Städte, Social Run Clubs, Kategorien und Artikel.",
+			Canonical:   "/suche.html",
+			Template:    "search.html",
+			OutFile:     "suche.html",
+		},
 		{
 			Title:       "Social Run Club Artikel",
 			Description: "Eine Übersicht über alle Social Run Club Artikel.",
@@ -194,17 +230,35 @@ func renderCityPages(data *Data, config Config, cssFiles, otherJS []string, umam
 				return fmt.Errorf("rendering club template %q: %w", club.Name, err)
 			}
 
-			imgName := filepath.Join(config.OutputDir, club.Image())
-			if err := copyClubImage(config, club, imgName); err != nil {
-				return fmt.Errorf("copying club image for club %q: %w", club.Name, err)
-			}
+			*sitemapUrls = append(*sitemapUrls, tdata.Canonical)
+		}
 
+		for _, cityTag := range city.TagPages {
+			tdata := createTemplateDataWithEntities(config, data, fmt.Sprintf("%s: Run Clubs und Lauftreffs in %s", cityTag.Tag.Name, city.Name), cityTag.MetaDescription(), createCanonicalURL(cityTag.Slug()), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS, city, nil, cityTag.Tag, nil)
+			tdata.CityTag = cityTag
+			fileName := filepath.Join(config.OutputDir, cityTag.Slug(), "index.html")
+			if err := utils.ExecuteTemplate("city-tag.html", fileName, tdata); err != nil {
+				return fmt.Errorf("rendering city tag template %q/%q: %w", city.Name, cityTag.Tag.Name, err)
+			}
 			*sitemapUrls = append(*sitemapUrls, tdata.Canonical)
 		}
 	}
 	return nil
 }
 
+func renderStatePages(data *Data, config Config, cssFiles, otherJS []string, umamiJS string, sitemapUrls *[]string) error {
+	for _, state := range data.States {
+		tdata := createTemplateData(config, data, fmt.Sprintf("Run Clubs und Lauftreffs in %s", state.Name), state.MetaDescription(), createCanonicalURL(state.Slug()), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS)
+		tdata.State = state
+		fileName := filepath.Join(config.OutputDir, state.Slug(), "index.html")
+		if err := utils.ExecuteTemplate("state.html", fileName, tdata); err != nil {
+			return fmt.Errorf("rendering state template %q: %w", state.Name, err)
+		}
+		*sitemapUrls = append(*sitemapUrls, tdata.Canonical)
+	}
+	return nil
+}
+
 func renderTagPages(data *Data, config Config, cssFiles, otherJS []string, umamiJS string, sitemapUrls *[]string) error {
 	for _, tag := range data.Tags {
 		tdata := createTemplateDataWithEntities(config, data, fmt.Sprintf("Run Clubs und Lauftreffs in der Kategorie %s", tag.Name), fmt.Sprintf("Eine Übersicht über alle Run Clubs und Lauftreffs in der Kategorie %s.", tag.Name), createCanonicalURL(tag.Slug()), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS, nil, nil, tag, nil)
@@ -217,14 +271,62 @@ func renderTagPages(data *Data, config Config, cssFiles, otherJS []string, umami
 	return nil
 }
 
+func renderWeekdayPages(data *Data, config Config, cssFiles, otherJS []string, umamiJS string, sitemapUrls *[]string) error {
+	weekdays := append([]*WeekdayRuns{}, data.Weekdays...)
+	for _, city := range data.Cities {
+		weekdays = append(weekdays, city.Weekdays...)
+	}
+	for _, weekday := range weekdays {
+		title := fmt.Sprintf("Social Run Clubs am %s", weekday.Name())
+		if weekday.City != nil {
+			title = fmt.Sprintf("Laufen am %s in %s - Social Run Clubs", weekday.Name(), weekday.City.Name)
+		}
+		tdata := createTemplateDataWithEntities(config, data, title, weekday.MetaDescription(), createCanonicalURL(weekday.Slug()), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS, weekday.City, nil, nil, nil)
+		tdata.Weekday = weekday
+		// weekdays without runs are linked from the other weekday pages, but
+		// should not be indexed
+		tdata.NoIndex = len(weekday.Runs) == 0
+		fileName := filepath.Join(config.OutputDir, weekday.Slug(), "index.html")
+		if err := utils.ExecuteTemplate("weekday.html", fileName, tdata); err != nil {
+			return fmt.Errorf("rendering weekday template %q: %w", weekday.Slug(), err)
+		}
+		if !tdata.NoIndex {
+			*sitemapUrls = append(*sitemapUrls, tdata.Canonical)
+		}
+	}
+	return nil
+}
+
 func renderPostPages(data *Data, config Config, cssFiles, otherJS []string, umamiJS string, sitemapUrls *[]string) error {
 	for _, post := range data.Posts {
-		tdata := createTemplateDataWithEntities(config, data, post.Title, fmt.Sprintf("Artikel: %s", post.Title), createCanonicalURL(post.Slug), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS, nil, nil, nil, post)
+		if post.coverFile != "" {
+			cover, err := utils.CopyHash(filepath.Join(postsDir, post.coverFile), filepath.Join(config.OutputDir, post.Slug, "cover.HASH"+filepath.Ext(post.coverFile)))
+			if err != nil {
+				return fmt.Errorf("copying cover image of post %q: %w", post.Title, err)
+			}
+			if post.Cover, err = trimPath(cover, config.OutputDir); err != nil {
+				return err
+			}
+		}
+
+		description := post.Description
+		if description == "" {
+			description = fmt.Sprintf("Artikel: %s", post.Title)
+		}
+		tdata := createTemplateDataWithEntities(config, data, post.Title, description, createCanonicalURL(post.Slug), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS, nil, nil, nil, post)
+		// drafts are only rendered for local previews
+		tdata.NoIndex = post.Draft
+		if err := renderPost(post, tdata); err != nil {
+			return fmt.Errorf("rendering post %q: %w", post.Title, err)
+		}
+
 		fileName := filepath.Join(config.OutputDir, post.Slug, "index.html")
-		if err := utils.ExecuteTemplate(post.TemplateFile, fileName, tdata); err != nil {
+		if err := utils.ExecuteTemplate("post.html", fileName, tdata); err != nil {
 			return fmt.Errorf("rendering post template %q: %w", post.Title, err)
 		}
-		*sitemapUrls = append(*sitemapUrls, tdata.Canonical)
+		if !post.Draft {
+			*sitemapUrls = append(*sitemapUrls, tdata.Canonical)
+		}
 	}
 	return nil
 }
@@ -242,6 +344,22 @@ func renderSpecialPages(data *Data, config Config, cssFiles, otherJS []string, u
 		return fmt.Errorf("rendering template %s: %w", "grid.html", err)
 	}
 
+	// coverage report
+	if data.Coverage != nil {
+		tdata = createTemplateData(config, data, "Abdeckung - Social Run Clubs", "Städte ohne Social Run Club in der Nähe", createCanonicalURL("/coverage.html"), config.Google.SubmitUrl, config.Google.ReportUrl, cssFiles, otherJS, umamiJS)
+		tdata.NoIndex = true
+		if err := utils.ExecuteTemplate("coverage.html", filepath.Join(config.OutputDir, "coverage.html"), tdata); err != nil {
+			return fmt.Errorf("rendering template %s: %w", "coverage.html", err)
+		}
+		geojson, err := data.Coverage.GeoJSON()
+		if err != nil {
+			return fmt.Errorf("creating coverage GeoJSON: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(config.OutputDir, "coverage.geojson"), geojson, 0644); err != nil {
+			return fmt.Errorf("writing coverage GeoJSON: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -278,35 +396,107 @@ func createSiteFiles(data *Data, config Config, sitemapUrls []string) error {
 	return nil
 }
 
-func copyPlaceholderImage(config Config, targetPath string) error {
-	placeholderImage := "static/placeholder.jpg"
-	if err := utils.CopyFile(placeholderImage, targetPath); err != nil {
-		return fmt.Errorf("copying placeholder image: %w", err)
+// FindClubImage returns the cached source image of the club, or "" if there is none.
+func FindClubImage(config Config, club *Club) string {
+	cachedImagName := filepath.Join(config.ImageDir, club.City.SanitizeName(), club.SanitizeName()+".jpg")
+	if utils.FileExists(cachedImagName) {
+		return cachedImagName
 	}
-	return nil
-}
 
-func copyClubImage(config Config, club *Club, targetPath string) error {
+	// images of older get_images versions are stored by Instagram profile
 	instagramProfile := club.InstagramProfile()
 	if instagramProfile != "" {
 		cachedImagName := filepath.Join(config.ImageDir, instagramProfile+".jpg")
 		if utils.FileExists(cachedImagName) {
-			if err := utils.CopyFile(cachedImagName, targetPath); err != nil {
-				return fmt.Errorf("copying Instagram image for club %q: %w", club.Name, err)
-			}
-			return nil
+			return cachedImagName
 		}
 	}
 
-	cachedImagName := filepath.Join(config.ImageDir, club.City.SanitizeName(), club.SanitizeName()+".jpg")
-	if utils.FileExists(cachedImagName) {
-		if err := utils.CopyFile(cachedImagName, targetPath); err != nil {
-			return fmt.Errorf("copying direct image for club %q: %w", club.Name, err)
+	return ""
+}
+
+// encoderFormats returns the formats with a configured and installed external
+// encoder (AVIF, WebP). The encoders are opt-in, so the site builds without
+// them; skipped formats are logged.
+func encoderFormats(config Config) []images.Format {
+	formats := make([]images.Format, 0, 2)
+	for _, f := range []struct {
+		name   string
+		format images.Format
+	}{
+		{"AVIF", images.AVIF(config.Images.AVIF)},
+		{"WebP", images.WebP(config.Images.WebP)},
+	} {
+		if f.format.Command == "" {
+			log.Printf("images: skipping %s, no encoder configured (Images.%s)", f.name, f.name)
+			continue
 		}
-		return nil
+		if err := f.format.Available(); err != nil {
+			log.Printf("images: skipping %s: %v", f.name, err)
+			continue
+		}
+		formats = append(formats, f.format)
 	}
+	return formats
+}
+
+// imageFormats returns the formats of the club images: the available encoder
+// formats with a JPEG fallback.
+func imageFormats(encoders []images.Format) []images.Format {
+	return append(slices.Clone(encoders), images.JPEG)
+}
+
+func processClubImage(config Config, club *Club, formats []images.Format) error {
+	set := images.NewSet(fmt.Sprintf("%s Logo", club.Name))
+	pattern := filepath.Join(config.OutputDir, club.Slug(), "img-SIZE.HASH.EXT")
+
+	sourceImage := FindClubImage(config, club)
+	var img image.Image
+	if sourceImage == "" {
+		// no image available -> generate a monogram
+		sourceImage = "monogram"
+		initials := images.Initials(club.Name)
+		background := images.MonogramColor(club.Slug())
+		svg := images.MonogramSVG(initials, background, images.Medium.Width)
+		if err := set.AddSVG(svg, config.OutputDir, filepath.Join(config.OutputDir, club.Slug(), "img.HASH.svg")); err != nil {
+			return fmt.Errorf("creating monogram: %w", err)
+		}
+		var err error
+		if img, err = images.Monogram(initials, background, images.Imported.Width); err != nil {
+			return fmt.Errorf("creating monogram: %w", err)
+		}
+	} else {
+		var err error
+		if img, err = images.Load(sourceImage); err != nil {
+			return err
+		}
+	}
+
+	if err := set.Add(img, config.OutputDir, pattern, images.LogoVariants, formats); err != nil {
+		return fmt.Errorf("processing image %s: %w", sourceImage, err)
+	}
+	if err := set.Add(img, config.OutputDir, pattern, []images.Variant{images.Share}, []images.Format{images.JPEG}); err != nil {
+		return fmt.Errorf("processing image %s: %w", sourceImage, err)
+	}
+	club.ImageSet = set
+
+	// keep a stable file name for the default size
+	defaultImage := filepath.Join(config.OutputDir, set.Path(images.Medium.Name, images.JPEG.Ext))
+	if err := utils.CopyFile(defaultImage, filepath.Join(config.OutputDir, club.Image())); err != nil {
+		return fmt.Errorf("copying image: %w", err)
+	}
+
+	return nil
+}
 
-	return copyPlaceholderImage(config, targetPath)
+func renderClubImages(data *Data, config Config, encoders []images.Format) error {
+	formats := imageFormats(encoders)
+	for _, club := range data.Clubs {
+		if err := processClubImage(config, club, formats); err != nil {
+			return fmt.Errorf("processing club image for club %q: %w", club.Name, err)
+		}
+	}
+	return nil
 }
 
 func Render(data *Data, cssFiles, jsFiles []string, config Config) error {
@@ -323,6 +513,29 @@ func Render(data *Data, cssFiles, jsFiles []string, config Config) error {
 	// collect all canonical URLs for creating a sitemap
 	sitemapUrls := make([]string, 0)
 
+	// club images are referenced by many pages, so they are created first
+	encoders := encoderFormats(config)
+	if err := renderClubImages(data, config, encoders); err != nil {
+		return err
+	}
+
+	if err := renderStaticMaps(data, config, encoders); err != nil {
+		return err
+	}
+
+	if err := renderSearchIndex(data, config); err != nil {
+		return err
+	}
+
+	if err := renderEventFeeds(data, config); err != nil {
+		return err
+	}
+
+	// posts before the static pages, as the overview shows their reading time
+	if err := renderPostPages(data, config, cssFiles, otherJS, umamiJS, &sitemapUrls); err != nil {
+		return err
+	}
+
 	if err := renderStaticPages(data, config, cssFiles, otherJS, umamiJS, &sitemapUrls); err != nil {
 		return err
 	}
@@ -331,11 +544,15 @@ func Render(data *Data, cssFiles, jsFiles []string, config Config) error {
 		return err
 	}
 
+	if err := renderStatePages(data, config, cssFiles, otherJS, umamiJS, &sitemapUrls); err != nil {
+		return err
+	}
+
 	if err := renderTagPages(data, config, cssFiles, otherJS, umamiJS, &sitemapUrls); err != nil {
 		return err
 	}
 
-	if err := renderPostPages(data, config, cssFiles, otherJS, umamiJS, &sitemapUrls); err != nil {
+	if err := renderWeekdayPages(data, config, cssFiles, otherJS, umamiJS, &sitemapUrls); err != nil {
 		return err
 	}
 
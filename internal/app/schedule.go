@@ -0,0 +1,226 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// Run is a regular run of a club.
+type Run struct {
+	Weekday time.Weekday
+	Start   string // e.g. "18:30"; empty if unknown
+}
+
+func (r Run) WeekdayName() string {
+	return weekdayNames[r.Weekday]
+}
+
+// parseWeekday parses German weekday names and abbreviations, e.g. "Montag",
+// "montags", "Mo" or "Mo.".
+func parseWeekday(s string) (time.Weekday, error) {
+	key := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+	for day, name := range weekdayNames {
+		name = strings.ToLower(name)
+		if key == name || key == name+"s" || key == name[:2] {
+			return time.Weekday(day), nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid weekday: %q", s)
+}
+
+var reStartTime = regexp.MustCompile(`(?i)^(\d{1,2})(?:[:.](\d{2}))?(?:\s*uhr)?$`)
+
+// parseStartTime parses times like "18:30", "18.30", "7:00 Uhr" or "18 Uhr" and
+// returns them as "18:30".
+func parseStartTime(s string) (string, error) {
+	match := reStartTime.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return "", fmt.Errorf("invalid start time: %q", s)
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	if hour > 23 || minute > 59 {
+		return "", fmt.Errorf("invalid start time: %q", s)
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), nil
+}
+
+// parseRuns parses the WEEKDAYS and START_TIME columns of the CLUBS sheet,
+// e.g. "Di, So" and "18:30, 10:00". A single start time applies to all
+// weekdays.
+func parseRuns(weekdaysRaw, startTimesRaw string) ([]Run, error) {
+	weekdays := utils.SplitAndTrim(weekdaysRaw, ",")
+	startTimes := utils.SplitAndTrim(startTimesRaw, ",")
+	if len(weekdays) == 0 {
+		if len(startTimes) > 0 {
+			return nil, fmt.Errorf("start time without weekday: %q", startTimesRaw)
+		}
+		return nil, nil
+	}
+	if len(startTimes) > 1 && len(startTimes) != len(weekdays) {
+		return nil, fmt.Errorf("%d start times for %d weekdays: %q", len(startTimes), len(weekdays), startTimesRaw)
+	}
+
+	runs := make([]Run, 0, len(weekdays))
+	for i, weekdayRaw := range weekdays {
+		weekday, err := parseWeekday(weekdayRaw)
+		if err != nil {
+			return nil, err
+		}
+		run := Run{Weekday: weekday}
+		if len(startTimes) > 0 {
+			startRaw := startTimes[0]
+			if len(startTimes) > 1 {
+				startRaw = startTimes[i]
+			}
+			if run.Start, err = parseStartTime(startRaw); err != nil {
+				return nil, err
+			}
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		// Monday first
+		a, b := (runs[i].Weekday+6)%7, (runs[j].Weekday+6)%7
+		if a != b {
+			return a < b
+		}
+		return runs[i].Start < runs[j].Start
+	})
+	return runs, nil
+}
+
+// ClubRun is a run of a club on a certain weekday.
+type ClubRun struct {
+	Club     *Club
+	Run      Run
+	Location *ClubLocation // nil for the club's main location
+}
+
+func (r ClubRun) City() *City {
+	if r.Location != nil {
+		return r.Location.City
+	}
+	return r.Club.City
+}
+
+func (r ClubRun) Search() string {
+	return strings.ToLower(fmt.Sprintf("%s %s", r.Club.Name, r.City().Name))
+}
+
+// MeetingPoint returns the meeting point of the run, or the neighbourhood if
+// the meeting point is unknown.
+func (r ClubRun) MeetingPoint() string {
+	meetingPoint, neighbourhood := r.Club.MeetingPoint, r.Club.Neighbourhood
+	if r.Location != nil {
+		meetingPoint, neighbourhood = r.Location.MeetingPoint, r.Location.Neighbourhood
+	}
+	if meetingPoint != "" {
+		return meetingPoint
+	}
+	return neighbourhood
+}
+
+func (r ClubRun) LatLon() *utils.LatLon {
+	if r.Location != nil {
+		return r.Location.LatLon
+	}
+	return r.Club.LatLon
+}
+
+// WeekdayRuns are the runs on a weekday, in a city or in all of Germany.
+type WeekdayRuns struct {
+	Weekday time.Weekday
+	City    *City // nil for all of Germany
+	Runs    []ClubRun
+}
+
+func (w *WeekdayRuns) Name() string {
+	return weekdayNames[w.Weekday]
+}
+
+// Number returns the weekday as in JavaScript's Date.getDay(), Sunday = 0.
+func (w *WeekdayRuns) Number() int {
+	return int(w.Weekday)
+}
+
+func (w *WeekdayRuns) Slug() string {
+	slug := fmt.Sprintf("/wochentag/%s", utils.SanitizeName(w.Name()))
+	if w.City != nil {
+		slug = w.City.Slug() + slug
+	}
+	return slug
+}
+
+func (w *WeekdayRuns) MetaDescription() string {
+	if w.City == nil {
+		return fmt.Sprintf("Alle %d Social Run Clubs in Deutschland, die %s laufen - mit Startzeit und Treffpunkt.", len(w.Runs), weekdayAdverb(w.Weekday))
+	}
+	return fmt.Sprintf("Laufen am %s in %s: alle %d Social Run Clubs mit Startzeit und Treffpunkt.", w.Name(), w.City.Name, len(w.Runs))
+}
+
+// collectWeekdayRuns groups the runs of the clubs by weekday, for all of
+// Germany (all weekdays) and per city (only weekdays with runs); runs of
+// further locations count for the location's city.
+func collectWeekdayRuns(data *Data) {
+	data.Weekdays = make([]*WeekdayRuns, 0, 7)
+	for i := 1; i <= 7; i++ {
+		data.Weekdays = append(data.Weekdays, &WeekdayRuns{Weekday: time.Weekday(i % 7)})
+	}
+
+	for _, city := range data.Cities {
+		city.Weekdays = nil
+		cityWeekdays := make(map[time.Weekday]*WeekdayRuns)
+		add := func(clubRun ClubRun) {
+			cityWeekday, found := cityWeekdays[clubRun.Run.Weekday]
+			if !found {
+				cityWeekday = &WeekdayRuns{Weekday: clubRun.Run.Weekday, City: city}
+				cityWeekdays[clubRun.Run.Weekday] = cityWeekday
+			}
+			cityWeekday.Runs = append(cityWeekday.Runs, clubRun)
+		}
+		for _, club := range city.AllClubs() {
+			if club.City == city {
+				for _, run := range club.Runs {
+					add(ClubRun{club, run, nil})
+				}
+			}
+			for _, location := range club.Locations {
+				if location.City == city {
+					for _, run := range location.Runs {
+						add(ClubRun{club, run, location})
+					}
+				}
+			}
+		}
+		for _, weekday := range data.Weekdays {
+			cityWeekday, found := cityWeekdays[weekday.Weekday]
+			if !found {
+				continue
+			}
+			sortClubRuns(cityWeekday.Runs)
+			city.Weekdays = append(city.Weekdays, cityWeekday)
+			weekday.Runs = append(weekday.Runs, cityWeekday.Runs...)
+		}
+	}
+}
+
+// sortClubRuns sorts the runs by start time (unknown last) and club name.
+func sortClubRuns(runs []ClubRun) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		a, b := runs[i].Run.Start, runs[j].Run.Start
+		if a != b {
+			return b == "" || (a != "" && a < b)
+		}
+		return runs[i].Club.SanitizeName() < runs[j].Club.SanitizeName()
+	})
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRuns(t *testing.T) {
+	runs, err := parseRuns("So, dienstags, Do.", "10 Uhr, 18.30, 7:05")
+	if err != nil {
+		t.Fatalf("parseRuns() error = %v", err)
+	}
+	expected := []Run{{time.Tuesday, "18:30"}, {time.Thursday, "07:05"}, {time.Sunday, "10:00"}}
+	if len(runs) != len(expected) {
+		t.Fatalf("parseRuns() = %v", runs)
+	}
+	for i := range expected {
+		if runs[i] != expected[i] {
+			t.Errorf("runs[%d] = %v, want %v", i, runs[i], expected[i])
+		}
+	}
+
+	if runs, err := parseRuns("Mo, Mi", "19:00"); err != nil || len(runs) != 2 || runs[1].Start != "19:00" {
+		t.Errorf("single start time: %v, %v", runs, err)
+	}
+	if runs, err := parseRuns("Samstag", ""); err != nil || len(runs) != 1 || runs[0].Start != "" {
+		t.Errorf("without start time: %v, %v", runs, err)
+	}
+	if runs, err := parseRuns("", ""); err != nil || runs != nil {
+		t.Errorf("empty: %v, %v", runs, err)
+	}
+
+	for _, tc := range [][2]string{
+		{"Funtag", ""},
+		{"Mo", "25:00"},
+		{"Mo", "abends"},
+		{"Mo, Di, Mi", "18:00, 19:00"},
+		{"", "18:00"},
+	} {
+		if _, err := parseRuns(tc[0], tc[1]); err == nil {
+			t.Errorf("parseRuns(%q, %q): expected error", tc[0], tc[1])
+		}
+	}
+}
+
+func TestCollectWeekdayRuns(t *testing.T) {
+	leipzig := &City{Name: "Leipzig"}
+	halle := &City{Name: "Halle"}
+	a := &Club{Name: "A", City: leipzig, Runs: []Run{{time.Tuesday, "19:00"}, {time.Sunday, "10:00"}}}
+	b := &Club{Name: "B", City: leipzig, Runs: []Run{{time.Tuesday, ""}}}
+	c := &Club{Name: "C", City: leipzig, Runs: []Run{{time.Tuesday, "07:00"}}}
+	d := &Club{Name: "D", City: halle, Runs: []Run{{time.Tuesday, "18:00"}}}
+	leipzig.Clubs = []*Club{a, b, c}
+	halle.Clubs = []*Club{d}
+	data := &Data{Cities: []*City{leipzig, halle}}
+
+	collectWeekdayRuns(data)
+
+	if len(data.Weekdays) != 7 || data.Weekdays[0].Weekday != time.Monday || data.Weekdays[6].Weekday != time.Sunday {
+		t.Fatalf("Weekdays = %v", data.Weekdays)
+	}
+	tuesday := data.Weekdays[1]
+	if len(tuesday.Runs) != 4 || tuesday.Slug() != "/wochentag/dienstag" {
+		t.Errorf("Tuesday = %v, %s", tuesday.Runs, tuesday.Slug())
+	}
+	if len(leipzig.Weekdays) != 2 || leipzig.Weekdays[0].Weekday != time.Tuesday || leipzig.Weekdays[1].Weekday != time.Sunday {
+		t.Fatalf("Leipzig weekdays = %v", leipzig.Weekdays)
+	}
+	runs := leipzig.Weekdays[0].Runs
+	if runs[0].Club != c || runs[1].Club != a || runs[2].Club != b {
+		t.Errorf("Leipzig Tuesday runs = %v", runs)
+	}
+	if slug := leipzig.Weekdays[0].Slug(); slug != "/leipzig/wochentag/dienstag" {
+		t.Errorf("Slug() = %q", slug)
+	}
+	if weekdays := a.Weekdays(); len(weekdays) != 2 || weekdays[1] != time.Sunday {
+		t.Errorf("Club.Weekdays() = %v", weekdays)
+	}
+}
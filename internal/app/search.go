@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// kinds of search index entries, the index of the entry's kind refers to this list
+var searchKinds = []string{"Stadt", "Club", "Kategorie", "Bundesland", "Artikel"}
+
+const (
+	searchKindCity = iota
+	searchKindClub
+	searchKindTag
+	searchKindState
+	searchKindPost
+)
+
+// searchEntry is an entry of the client-side search index; it is encoded as
+// compact JSON array: [kind, name, url, detail, terms, weight].
+type searchEntry struct {
+	Kind   int
+	Name   string
+	URL    string
+	Detail string
+	Terms  string // normalized search terms, separated by spaces
+	Weight int    // ranks entries with the same match quality, e.g. number of clubs
+}
+
+func (e searchEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Kind, e.Name, e.URL, e.Detail, e.Terms, e.Weight})
+}
+
+type searchIndex struct {
+	Kinds   []string      `json:"k"`
+	Entries []searchEntry `json:"e"`
+}
+
+// searchTerms normalizes the texts like SanitizeName (lowercase, folded
+// umlauts, e.g. "Köln" => "koeln") and returns their unique words.
+func searchTerms(texts ...string) string {
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, text := range texts {
+		for _, term := range strings.Split(utils.SanitizeName(text), "-") {
+			if term != "" && !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+func clubsLabel(n int) string {
+	switch n {
+	case 0:
+		return "noch keine Clubs"
+	case 1:
+		return "1 Club"
+	}
+	return fmt.Sprintf("%d Clubs", n)
+}
+
+// buildSearchIndex collects the clubs, cities, tags, states and posts; basePath
+// maps site paths to URLs, see utils.BasePathFunc.
+func buildSearchIndex(data *Data, basePath func(string) string) searchIndex {
+	index := searchIndex{Kinds: searchKinds, Entries: make([]searchEntry, 0)}
+	add := func(kind int, name, slug, detail, terms string, weight int) {
+		index.Entries = append(index.Entries, searchEntry{kind, name, basePath(slug), detail, terms, weight})
+	}
+
+	for _, city := range data.Cities {
+		detail := clubsLabel(len(city.AllClubs()))
+		texts := []string{city.Name, city.District}
+		if city.State != nil {
+			texts = append(texts, city.State.Name)
+			if city.State.Name != city.Name {
+				detail = fmt.Sprintf("%s, %s", city.State.Name, detail)
+			}
+		}
+		add(searchKindCity, city.Name, city.Slug(), detail, searchTerms(texts...), len(city.AllClubs()))
+
+		for _, club := range city.Clubs {
+			texts := []string{club.Name, city.Name, club.Neighbourhood}
+			for _, location := range club.Locations {
+				texts = append(texts, location.City.Name, location.Neighbourhood)
+			}
+			for _, tag := range club.Tags {
+				texts = append(texts, tag.Name, tag.RawName)
+				texts = append(texts, tag.Aliases...)
+			}
+			detail := city.Name
+			if club.Neighbourhood != "" {
+				detail = fmt.Sprintf("%s-%s", city.Name, club.Neighbourhood)
+			}
+			add(searchKindClub, club.Name, club.Slug(), detail, searchTerms(texts...), 1)
+		}
+
+		for _, cityTag := range city.TagPages {
+			texts := append([]string{cityTag.Tag.Name, city.Name}, cityTag.Tag.Aliases...)
+			add(searchKindTag, fmt.Sprintf("%s in %s", cityTag.Tag.Name, city.Name), cityTag.Slug(), clubsLabel(len(cityTag.Clubs)), searchTerms(texts...), len(cityTag.Clubs))
+		}
+	}
+
+	for _, tag := range data.Tags {
+		texts := append([]string{tag.Name, tag.RawName}, tag.Aliases...)
+		add(searchKindTag, tag.Name, tag.Slug(), clubsLabel(len(tag.Clubs)), searchTerms(texts...), len(tag.Clubs))
+	}
+
+	for _, state := range data.States {
+		add(searchKindState, state.Name, state.Slug(), clubsLabel(state.NumberOfClubs()), searchTerms(state.Name), state.NumberOfClubs())
+	}
+
+	for _, post := range data.Posts {
+		if post.Draft {
+			continue
+		}
+		texts := append([]string{post.Title}, post.Tags...)
+		add(searchKindPost, post.Title, post.Slug, post.PublishedLabel(), searchTerms(texts...), 0)
+	}
+
+	return index
+}
+
+// renderSearchIndex writes the search index as script, which sets the global
+// variable "searchIndex" (a script also works for local builds without web
+// server), and stores its path in data.SearchIndex.
+func renderSearchIndex(data *Data, config Config) error {
+	basePath := utils.BasePathFunc(TemplateData{isRemoteTarget: config.IsRemoteTarget, basePath: config.OutputDir})
+	buf, err := json.Marshal(buildSearchIndex(data, basePath))
+	if err != nil {
+		return fmt.Errorf("encoding search index: %w", err)
+	}
+	script := append([]byte("window.searchIndex="), buf...)
+	script = append(script, ";\n"...)
+
+	file, err := utils.WriteHash(filepath.Join(config.OutputDir, "static", "search-index.HASH.js"), script)
+	if err != nil {
+		return fmt.Errorf("writing search index: %w", err)
+	}
+	data.SearchIndex, err = trimPath(file, config.OutputDir)
+	return err
+}
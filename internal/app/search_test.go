@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchTerms(t *testing.T) {
+	if terms := searchTerms("Köln Läufer", "", "Köln-Ehrenfeld", "Straße"); terms != "koeln laeufer ehrenfeld strasse" {
+		t.Errorf("searchTerms() = %q", terms)
+	}
+}
+
+func TestBuildSearchIndex(t *testing.T) {
+	data := &Data{}
+	koeln := &City{Name: "Köln", District: ""}
+	leer := &City{Name: "Leer"}
+	data.Cities = []*City{koeln, leer}
+	data.setCityState(koeln, "Nordrhein-Westfalen")
+	trail := data.getOrAddTag("Trail")
+	data.addTagAlias(trail, "Gelände")
+	club := &Club{Name: "Köln Läufer", City: koeln, Neighbourhood: "Ehrenfeld", Tags: []*Tag{trail}}
+	koeln.Clubs = []*Club{club}
+	trail.Clubs = []*Club{club}
+	data.Posts = []*Post{
+		{Title: "Laufen für Anfänger", Slug: "/post/anfaenger", Tags: []string{"einstieg"}, Published: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Title: "Entwurf", Slug: "/post/entwurf", Draft: true},
+	}
+
+	index := buildSearchIndex(data, func(p string) string { return p + "/" })
+	buf, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"k":["Stadt","Club","Kategorie","Bundesland","Artikel"]`,
+		`[0,"Köln","/koeln/","Nordrhein-Westfalen, 1 Club","koeln nordrhein westfalen",1]`,
+		`[0,"Leer","/leer/","noch keine Clubs","leer",0]`,
+		`[1,"Köln Läufer","/koeln/koeln-laeufer/","Köln-Ehrenfeld","koeln laeufer ehrenfeld trail gelaende",1]`,
+		`[2,"Trail","/tag/trail/","1 Club","trail gelaende",1]`,
+		`[3,"Nordrhein-Westfalen",`,
+		`[4,"Laufen für Anfänger","/post/anfaenger/","1. März 2025","laufen fuer anfaenger einstieg",0]`,
+	} {
+		if !strings.Contains(string(buf), expected) {
+			t.Errorf("index does not contain %s:\n%s", expected, buf)
+		}
+	}
+	if strings.Contains(string(buf), "Entwurf") {
+		t.Errorf("index contains draft post:\n%s", buf)
+	}
+}
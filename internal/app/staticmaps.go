@@ -0,0 +1,137 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/flopp/socialrunclubs-de/internal/images"
+	"github.com/flopp/socialrunclubs-de/internal/staticmap"
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// germanyCenter is the center of the city maps, matching GERMANY_BOUNDS of
+// the interactive maps in script.js.
+var germanyCenter = utils.LatLon{Lat: 51.2, Lon: 10.4}
+
+// StaticMap is a pre-rendered map image, shown instead of an interactive map
+// until the visitor clicks on it.
+type StaticMap struct {
+	Picture     images.Picture
+	Attribution string
+}
+
+// newStaticMapRenderer returns the configured map renderers for clubs and
+// cities, or nil if static maps are disabled.
+func newStaticMapRenderer(config Config) (club, city *staticmap.Map) {
+	var source staticmap.TileSource
+	if config.StaticMaps.TileDir != "" {
+		source = staticmap.DirTiles(config.StaticMaps.TileDir)
+	} else if config.StaticMaps.TileURL != "" {
+		userAgent := config.StaticMaps.UserAgent
+		if userAgent == "" {
+			userAgent = "socialrunclubs.de"
+		}
+		source = staticmap.NewHTTPTiles(config.StaticMaps.TileURL, filepath.Join(config.CacheDir, "tiles"), userAgent)
+	} else {
+		return nil, nil
+	}
+	source = staticmap.NewMemoryCache(source)
+
+	width, height := config.StaticMaps.Width, config.StaticMaps.Height
+	if width <= 0 {
+		width = 800
+	}
+	if height <= 0 {
+		height = 400
+	}
+	clubZoom, cityZoom := config.StaticMaps.ClubZoom, config.StaticMaps.CityZoom
+	if clubZoom <= 0 {
+		clubZoom = 13
+	}
+	if cityZoom <= 0 {
+		cityZoom = 5
+	}
+	club = &staticmap.Map{Width: width, Height: height, Zoom: clubZoom, Tiles: source}
+	city = &staticmap.Map{Width: width, Height: height, Zoom: cityZoom, Tiles: source}
+	return club, city
+}
+
+// staticMapFormats returns the output formats of the map images: WebP (if
+// available) with a PNG fallback, as JPEG blurs the map's lines and labels.
+func staticMapFormats(encoders []images.Format) []images.Format {
+	formats := make([]images.Format, 0, 2)
+	for _, format := range encoders {
+		if format.Type == "image/webp" {
+			formats = append(formats, format)
+		}
+	}
+	return append(formats, images.PNG)
+}
+
+func renderStaticMap(m *staticmap.Map, center, marker utils.LatLon, alt string, dir string, formats []images.Format, config Config) (*StaticMap, error) {
+	img, err := m.Render(center, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	picture := images.Picture{Alt: alt, Width: m.Width, Height: m.Height, Loading: images.LoadLazy}
+	for _, format := range formats {
+		out, err := format.Save(img, filepath.Join(dir, "map.HASH"+format.Ext))
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(config.OutputDir, out)
+		if err != nil {
+			return nil, err
+		}
+		path := "/" + filepath.ToSlash(rel)
+		if format.Ext == images.PNG.Ext {
+			picture.Src = path
+		} else {
+			picture.Sources = append(picture.Sources, images.Source{Type: format.Type, Srcset: []images.Candidate{{Path: path, Density: "1x"}}})
+		}
+	}
+
+	attribution := config.StaticMaps.Attribution
+	if attribution == "" {
+		attribution = "© OpenStreetMap-Mitwirkende"
+	}
+	return &StaticMap{Picture: picture, Attribution: attribution}, nil
+}
+
+// renderStaticMaps creates the map images of the club and city pages; clubs
+// without known meeting point are shown at their city.
+func renderStaticMaps(data *Data, config Config, encoders []images.Format) error {
+	clubMap, cityMap := newStaticMapRenderer(config)
+	if clubMap == nil {
+		return nil
+	}
+	formats := staticMapFormats(encoders)
+
+	for _, club := range data.Clubs {
+		location := club.Location()
+		if location == nil {
+			continue
+		}
+		dir := filepath.Join(config.OutputDir, club.Slug())
+		staticMap, err := renderStaticMap(clubMap, *location, *location, fmt.Sprintf("Karte: %s", club.Name), dir, formats, config)
+		if err != nil {
+			return fmt.Errorf("rendering map for club %q: %w", club.Name, err)
+		}
+		club.StaticMap = staticMap
+	}
+
+	for _, city := range data.Cities {
+		if city.LatLon == nil {
+			continue
+		}
+		dir := filepath.Join(config.OutputDir, city.Slug())
+		staticMap, err := renderStaticMap(cityMap, germanyCenter, *city.LatLon, fmt.Sprintf("Karte: Lage von %s in Deutschland", city.Name), dir, formats, config)
+		if err != nil {
+			return fmt.Errorf("rendering map for city %q: %w", city.Name, err)
+		}
+		city.StaticMap = staticMap
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+const defaultCityTagMinClubs = 3
+
+// tagKey normalizes tag names and aliases for lookups, e.g. "Anfänger" => "anfaenger".
+func tagKey(name string) string {
+	return utils.SanitizeName(name)
+}
+
+// lookupTag returns the tag with the given name or alias, or nil if there is none.
+func (d *Data) lookupTag(name string) *Tag {
+	return d.TagMap[tagKey(name)]
+}
+
+func (d *Data) addTagAlias(tag *Tag, alias string) {
+	key := tagKey(alias)
+	if other, found := d.TagMap[key]; found {
+		if other != tag {
+			d.addFinding(tag.RawName, "TAGS: alias %q is already used by tag %q", alias, other.RawName)
+		}
+		return
+	}
+	tag.Aliases = append(tag.Aliases, alias)
+	d.TagMap[key] = tag
+	// old links to the alias, e.g. from before the tags were merged
+	from := fmt.Sprintf("/tag/%s", key)
+	d.redirect(from, tag.Slug())
+	d.redirect(from+"/", tag.Slug())
+	d.redirect(from+"/index.html", tag.Slug())
+}
+
+// Ancestors returns the parent tags, the direct parent first.
+func (t *Tag) Ancestors() []*Tag {
+	ancestors := make([]*Tag, 0)
+	for parent := t.Parent; parent != nil; parent = parent.Parent {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// resolveTags links the tags to their parents and assigns the tags of the
+// CLUBS sheet to the clubs. Tags are only created by the TAGS sheet; unknown
+// tags are reported as findings.
+func resolveTags(data *Data) {
+	for _, tag := range data.Tags {
+		if tag.parentRaw == "" {
+			continue
+		}
+		parent := data.lookupTag(tag.parentRaw)
+		if parent == nil {
+			data.addFinding(tag.RawName, "TAGS: unknown parent tag %q", tag.parentRaw)
+			continue
+		}
+		// reject cycles, e.g. "a => b => a"
+		cycle := parent == tag
+		for ancestor := parent.Parent; ancestor != nil && !cycle; ancestor = ancestor.Parent {
+			cycle = ancestor == tag
+		}
+		if cycle {
+			data.addFinding(tag.RawName, "TAGS: parent tag %q creates a cycle", tag.parentRaw)
+			continue
+		}
+		tag.Parent = parent
+		parent.Children = append(parent.Children, tag)
+	}
+	for _, tag := range data.Tags {
+		sort.Slice(tag.Children, func(i, j int) bool {
+			return tag.Children[i].Slug() < tag.Children[j].Slug()
+		})
+	}
+
+	for _, city := range data.Cities {
+		for _, club := range city.Clubs {
+			club.Tags = make([]*Tag, 0, len(club.tagNames))
+			assigned := make(map[*Tag]bool)
+			for _, name := range club.tagNames {
+				tag := data.lookupTag(name)
+				if tag == nil {
+					data.addFinding(club.Name, "CLUBS: unknown tag %q", name)
+					continue
+				}
+				if assigned[tag] {
+					continue
+				}
+				assigned[tag] = true
+				club.Tags = append(club.Tags, tag)
+			}
+
+			// the club is also listed on the pages of the parent tags
+			listed := make(map[*Tag]bool)
+			for _, tag := range club.Tags {
+				for _, t := range append([]*Tag{tag}, tag.Ancestors()...) {
+					if !listed[t] {
+						listed[t] = true
+						t.Clubs = append(t.Clubs, club)
+					}
+				}
+			}
+		}
+	}
+}
+
+// CityTag is a tag restricted to the clubs of one city, e.g. "beginner-friendly
+// clubs in Hamburg".
+type CityTag struct {
+	City  *City
+	Tag   *Tag
+	Clubs []*Club
+}
+
+func (ct *CityTag) Slug() string {
+	return fmt.Sprintf("%s/tag/%s", ct.City.Slug(), utils.SanitizeName(ct.Tag.RawName))
+}
+
+func (ct *CityTag) MetaDescription() string {
+	return fmt.Sprintf("Alle %d Social Run Clubs und Lauftreffs der Kategorie %s in %s.", len(ct.Clubs), ct.Tag.Name, ct.City.Name)
+}
+
+// AnnotateCityTags collects the tags of each city that have enough clubs for
+// an own page, e.g. /hamburg/tag/anfaenger/.
+func AnnotateCityTags(data *Data, config Config) error {
+	minClubs := config.CityTags.MinClubs
+	if minClubs <= 0 {
+		minClubs = defaultCityTagMinClubs
+	}
+
+	for _, tag := range data.Tags {
+		tag.CityPages = nil
+	}
+	for _, city := range data.Cities {
+		city.TagPages = nil
+		clubs := city.AllClubs()
+		cityClubs := make(map[*Tag][]*Club)
+		for _, tag := range data.Tags {
+			for _, club := range tag.Clubs {
+				if slices.Contains(clubs, club) {
+					cityClubs[tag] = append(cityClubs[tag], club)
+				}
+			}
+		}
+		for _, tag := range data.Tags {
+			tagClubs := cityClubs[tag]
+			// skip tags that would just repeat the city page or a child tag's page
+			if len(tagClubs) < minClubs || len(tagClubs) == len(clubs) {
+				continue
+			}
+			repeatsChild := false
+			for _, child := range tag.Children {
+				repeatsChild = repeatsChild || len(cityClubs[child]) == len(tagClubs)
+			}
+			if repeatsChild {
+				continue
+			}
+			cityTag := &CityTag{City: city, Tag: tag, Clubs: tagClubs}
+			city.TagPages = append(city.TagPages, cityTag)
+			tag.CityPages = append(tag.CityPages, cityTag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveTags(t *testing.T) {
+	data := &Data{}
+	genuss := data.getOrAddTag("Genuss")
+	kaffee := data.getOrAddTag("Kaffee")
+	kaffee.parentRaw = "genuss"
+	data.addTagAlias(kaffee, "Coffee")
+	data.addTagAlias(genuss, "kaffee")
+	a := data.getOrAddTag("A")
+	b := data.getOrAddTag("B")
+	a.parentRaw = "B"
+	b.parentRaw = "A"
+	orphan := data.getOrAddTag("Orphan")
+	orphan.parentRaw = "Unbekannt"
+
+	berlin := &City{Name: "Berlin"}
+	club := &Club{Name: "Club", City: berlin, tagNames: []string{"coffee", "Kaffee", "Kafee"}}
+	berlin.Clubs = []*Club{club}
+	data.Cities = []*City{berlin}
+
+	resolveTags(data)
+
+	if len(club.Tags) != 1 || club.Tags[0] != kaffee {
+		t.Errorf("club.Tags = %v", club.Tags)
+	}
+	if len(kaffee.Clubs) != 1 || len(genuss.Clubs) != 1 {
+		t.Errorf("kaffee.Clubs = %v, genuss.Clubs = %v", kaffee.Clubs, genuss.Clubs)
+	}
+	if kaffee.Parent != genuss || len(genuss.Children) != 1 || genuss.Children[0] != kaffee {
+		t.Errorf("kaffee.Parent = %v, genuss.Children = %v", kaffee.Parent, genuss.Children)
+	}
+	if (a.Parent == nil) == (b.Parent == nil) {
+		t.Errorf("cycle: a.Parent = %v, b.Parent = %v", a.Parent, b.Parent)
+	}
+	if data.Redirects["/tag/coffee/"] != "/tag/kaffee" {
+		t.Errorf("Redirects = %v", data.Redirects)
+	}
+
+	findings := make([]string, 0)
+	for _, finding := range data.Findings {
+		findings = append(findings, finding.String())
+	}
+	for _, expected := range []string{
+		`Genuss: TAGS: alias "kaffee" is already used by tag "Kaffee"`,
+		`Orphan: TAGS: unknown parent tag "Unbekannt"`,
+		`creates a cycle`,
+		`Club: CLUBS: unknown tag "Kafee"`,
+	} {
+		if !strings.Contains(strings.Join(findings, "\n"), expected) {
+			t.Errorf("findings %v do not contain %q", findings, expected)
+		}
+	}
+}
+
+func TestAnnotateCityTags(t *testing.T) {
+	data := &Data{}
+	genuss := data.getOrAddTag("Genuss")
+	kaffee := data.getOrAddTag("Kaffee")
+	kaffee.parentRaw = "Genuss"
+	trail := data.getOrAddTag("Trail")
+	hamburg := &City{Name: "Hamburg"}
+	data.Cities = []*City{hamburg}
+	for i, tags := range [][]string{{"Kaffee", "Trail"}, {"Kaffee", "Trail"}, {"Trail"}, {}} {
+		club := &Club{Name: string(rune('A' + i)), City: hamburg, tagNames: tags}
+		hamburg.Clubs = append(hamburg.Clubs, club)
+	}
+	resolveTags(data)
+
+	var config Config
+	config.CityTags.MinClubs = 2
+	if err := AnnotateCityTags(data, config); err != nil {
+		t.Fatal(err)
+	}
+	// Genuss only repeats Kaffee
+	if len(hamburg.TagPages) != 2 || hamburg.TagPages[0].Tag != kaffee || hamburg.TagPages[1].Tag != trail || len(genuss.CityPages) != 0 {
+		t.Fatalf("TagPages = %v", hamburg.TagPages)
+	}
+	if slug := hamburg.TagPages[0].Slug(); slug != "/hamburg/tag/kaffee" {
+		t.Errorf("Slug() = %q", slug)
+	}
+	if len(trail.CityPages) != 1 || len(trail.CityPages[0].Clubs) != 3 {
+		t.Errorf("trail.CityPages = %v", trail.CityPages)
+	}
+
+	config.CityTags.MinClubs = 0
+	if err := AnnotateCityTags(data, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(hamburg.TagPages) != 1 || hamburg.TagPages[0].Tag != trail {
+		t.Errorf("TagPages with default minimum = %v", hamburg.TagPages)
+	}
+}
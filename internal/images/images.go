@@ -0,0 +1,157 @@
+package images
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const jpegQuality = 85
+
+// Variant describes an output image: its pixel size and whether the source is
+// cropped to fill the size (default) or padded onto a white background.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Pad    bool
+}
+
+var (
+	Small    = Variant{Name: "50", Width: 50, Height: 50}
+	Medium   = Variant{Name: "100", Width: 100, Height: 100}
+	Large    = Variant{Name: "200", Width: 200, Height: 200}
+	Share    = Variant{Name: "share", Width: 1200, Height: 630, Pad: true}
+	Imported = Variant{Name: "import", Width: 400, Height: 400}
+)
+
+// LogoVariants are the logo sizes used by the templates: 50px and 100px
+// (100px and 200px being their 2x versions).
+var LogoVariants = []Variant{Small, Medium, Large}
+
+// Load decodes a JPEG, PNG or WebP image file.
+func Load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// centerCrop returns the largest centered region of r with the aspect ratio width:height.
+func centerCrop(r image.Rectangle, width, height int) image.Rectangle {
+	w, h := r.Dx(), r.Dy()
+	if w*height > h*width {
+		// too wide
+		cw := h * width / height
+		x := r.Min.X + (w-cw)/2
+		return image.Rect(x, r.Min.Y, x+cw, r.Max.Y)
+	}
+	// too high
+	ch := w * height / width
+	y := r.Min.Y + (h-ch)/2
+	return image.Rect(r.Min.X, y, r.Max.X, y+ch)
+}
+
+// Resize scales img to the variant's size, either cropping to the target
+// aspect ratio or padding the complete image onto a white canvas.
+func Resize(img image.Image, v Variant) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, v.Width, v.Height))
+	src := img.Bounds()
+
+	if !v.Pad {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, centerCrop(src, v.Width, v.Height), draw.Src, nil)
+		return dst
+	}
+
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	w, h := v.Width, v.Height
+	if src.Dx()*v.Height > src.Dy()*v.Width {
+		h = src.Dy() * v.Width / src.Dx()
+	} else {
+		w = src.Dx() * v.Height / src.Dy()
+	}
+	x := (v.Width - w) / 2
+	y := (v.Height - h) / 2
+	draw.CatmullRom.Scale(dst, image.Rect(x, y, x+w, y+h), img, src, draw.Over, nil)
+	return dst
+}
+
+func encode(img image.Image, dst string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch strings.ToLower(filepath.Ext(dst)) {
+	case ".jpg", ".jpeg":
+		return jpeg.Encode(out, img, &jpeg.Options{Quality: jpegQuality})
+	case ".png":
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		return encoder.Encode(out, img)
+	default:
+		return fmt.Errorf("unsupported image format: %s", dst)
+	}
+}
+
+// Save encodes img based on the file extension of dst. Since the image is
+// re-encoded, no metadata of the source file ends up in the output. If dst
+// contains "HASH", it is replaced by the content hash of the encoded image.
+// The function returns the actual output filename.
+func Save(img image.Image, dst string) (string, error) {
+	if !strings.Contains(dst, "HASH") {
+		if err := utils.MakeDir(filepath.Dir(dst)); err != nil {
+			return "", fmt.Errorf("create dst dir: %w", err)
+		}
+		if err := encode(img, dst); err != nil {
+			return "", fmt.Errorf("encode %s: %w", dst, err)
+		}
+		return dst, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "images")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpFile := filepath.Join(tmpDir, "image"+filepath.Ext(dst))
+	if err := encode(img, tmpFile); err != nil {
+		return "", fmt.Errorf("encode %s: %w", dst, err)
+	}
+	return utils.CopyHash(tmpFile, dst)
+}
+
+// Import loads the image src, crops it to a square (downscaling large images)
+// and stores it as normalized image file in dst. The function returns the
+// size of the stored image.
+func Import(src, dst string) (image.Point, error) {
+	img, err := Load(src)
+	if err != nil {
+		return image.Point{}, err
+	}
+
+	v := Imported
+	v.Width = min(v.Width, img.Bounds().Dx(), img.Bounds().Dy())
+	v.Height = v.Width
+	if _, err := Save(Resize(img, v), dst); err != nil {
+		return image.Point{}, err
+	}
+	return image.Pt(v.Width, v.Height), nil
+}
@@ -0,0 +1,116 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testImage(width, height int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCenterCrop(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        image.Rectangle
+		width    int
+		height   int
+		expected image.Rectangle
+	}{
+		{"wide to square", image.Rect(0, 0, 200, 100), 1, 1, image.Rect(50, 0, 150, 100)},
+		{"high to square", image.Rect(0, 0, 100, 300), 1, 1, image.Rect(0, 100, 100, 200)},
+		{"square to square", image.Rect(0, 0, 100, 100), 1, 1, image.Rect(0, 0, 100, 100)},
+		{"offset", image.Rect(10, 10, 210, 110), 1, 1, image.Rect(60, 10, 160, 110)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := centerCrop(tt.r, tt.width, tt.height)
+			if result != tt.expected {
+				t.Errorf("centerCrop(%v, %d, %d) = %v, want %v", tt.r, tt.width, tt.height, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	img := testImage(300, 200, color.Black)
+
+	for _, v := range append(LogoVariants, Share) {
+		result := Resize(img, v)
+		if result.Bounds().Dx() != v.Width || result.Bounds().Dy() != v.Height {
+			t.Errorf("Resize(%s) size = %v, want %dx%d", v.Name, result.Bounds(), v.Width, v.Height)
+		}
+	}
+
+	// padded variants keep the background color at the borders
+	result := Resize(testImage(100, 100, color.Black), Share)
+	if r, g, b, _ := result.At(0, 0).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("Resize(share) corner color = %v, want white", result.At(0, 0))
+	}
+	if r, g, b, _ := result.At(600, 315).RGBA(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("Resize(share) center color = %v, want black", result.At(600, 315))
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	img := testImage(20, 10, color.RGBA{255, 0, 0, 255})
+
+	for _, name := range []string{"image.jpg", "image.png", "sub/image.HASH.png"} {
+		t.Run(name, func(t *testing.T) {
+			out, err := Save(img, filepath.Join(tmpDir, name))
+			if err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if strings.Contains(out, "HASH") {
+				t.Errorf("Save() = %q, placeholder not replaced", out)
+			}
+
+			loaded, err := Load(out)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if loaded.Bounds().Dx() != 20 || loaded.Bounds().Dy() != 10 {
+				t.Errorf("Load() size = %v, want 20x10", loaded.Bounds())
+			}
+		})
+	}
+
+	if _, err := Save(img, filepath.Join(tmpDir, "image.gif")); err == nil {
+		t.Error("Save() with unsupported format: expected error")
+	}
+}
+
+func TestImportDoesNotUpscale(t *testing.T) {
+	tmpDir := t.TempDir()
+	src, err := Save(testImage(120, 80, color.White), filepath.Join(tmpDir, "src.png"))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	dst := filepath.Join(tmpDir, "dst.jpg")
+	size, err := Import(src, dst)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if size != image.Pt(80, 80) {
+		t.Errorf("Import() = %v, want 80x80", size)
+	}
+	loaded, err := Load(dst)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Bounds().Dx() != 80 || loaded.Bounds().Dy() != 80 {
+		t.Errorf("Import() size = %v, want 80x80", loaded.Bounds())
+	}
+}
@@ -0,0 +1,70 @@
+package images
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// Record describes the provenance of a club image.
+type Record struct {
+	Image     string    // image file, relative to the image directory
+	Source    string    // source platform, e.g. "instagram"
+	SourceURL string    // URL (or file) the image was taken from
+	FetchedAt time.Time // time of the last successful fetch
+	Hash      string    // content hash of the stored image
+	Width     int
+	Height    int
+	Error     string    // error of the last failed fetch (if any)
+	FailedAt  time.Time // time of the last failed fetch
+}
+
+// IsStale checks whether the image should be fetched again.
+func (r *Record) IsStale(now time.Time, maxAge time.Duration) bool {
+	if r == nil || r.FetchedAt.IsZero() {
+		return true
+	}
+	return now.Sub(r.FetchedAt) > maxAge
+}
+
+// Manifest maps club slugs to image records.
+type Manifest map[string]*Record
+
+func LoadManifest(fileName string) (Manifest, error) {
+	manifest := make(Manifest)
+	if !utils.FileExists(fileName) {
+		return manifest, nil
+	}
+
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (m Manifest) Save(fileName string) error {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(fileName, buf, 0644)
+}
+
+// Failed returns the sorted slugs of all records with a failed last fetch.
+func (m Manifest) Failed() []string {
+	slugs := make([]string, 0)
+	for slug, record := range m {
+		if record.Error != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Strings(slugs)
+	return slugs
+}
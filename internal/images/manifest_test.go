@@ -0,0 +1,59 @@
+package images
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordIsStale(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	maxAge := 24 * time.Hour
+
+	var missing *Record
+	if !missing.IsStale(now, maxAge) {
+		t.Error("IsStale() of nil record = false, want true")
+	}
+	if !(&Record{Error: "failed", FailedAt: now}).IsStale(now, maxAge) {
+		t.Error("IsStale() of never fetched record = false, want true")
+	}
+	if (&Record{FetchedAt: now.Add(-time.Hour)}).IsStale(now, maxAge) {
+		t.Error("IsStale() of recent record = true, want false")
+	}
+	if !(&Record{FetchedAt: now.Add(-48 * time.Hour)}).IsStale(now, maxAge) {
+		t.Error("IsStale() of old record = false, want true")
+	}
+}
+
+func TestManifestSaveAndLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sub", "images.json")
+
+	manifest, err := LoadManifest(fileName)
+	if err != nil {
+		t.Fatalf("LoadManifest() of missing file error = %v", err)
+	}
+	if len(manifest) != 0 {
+		t.Fatalf("LoadManifest() of missing file = %v, want empty", manifest)
+	}
+
+	fetchedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	manifest["/berlin/a"] = &Record{Image: "a.jpg", Source: "instagram", SourceURL: "https://example.com/a.jpg", FetchedAt: fetchedAt, Hash: "0123456789abcdef", Width: 400, Height: 400}
+	manifest["/koeln/b"] = &Record{Image: "koeln/b.jpg", Error: "no image found", FailedAt: fetchedAt}
+	manifest["/berlin/c"] = &Record{Image: "c.jpg", Error: "timeout", FailedAt: fetchedAt}
+	if err := manifest.Save(fileName); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadManifest(fileName)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, manifest) {
+		t.Errorf("LoadManifest() = %+v, want %+v", loaded, manifest)
+	}
+
+	if failed := loaded.Failed(); !reflect.DeepEqual(failed, []string{"/berlin/c", "/koeln/b"}) {
+		t.Errorf("Failed() = %v", failed)
+	}
+}
@@ -0,0 +1,146 @@
+package images
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// genericWords are skipped when determining the initials of a club name,
+// unless the name consists of nothing else.
+var genericWords = map[string]bool{
+	"club":      true,
+	"crew":      true,
+	"der":       true,
+	"die":       true,
+	"lauftreff": true,
+	"run":       true,
+	"runclub":   true,
+	"runners":   true,
+	"running":   true,
+	"social":    true,
+	"the":       true,
+}
+
+// Initials returns up to two uppercase initials of the given name.
+func Initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	significant := make([]string, 0, len(words))
+	for _, word := range words {
+		if !genericWords[strings.ToLower(word)] {
+			significant = append(significant, word)
+		}
+	}
+	if len(significant) == 0 {
+		significant = words
+	}
+
+	initials := make([]rune, 0, 2)
+	for _, word := range significant {
+		if len(initials) == 2 {
+			break
+		}
+		initials = append(initials, unicode.ToUpper([]rune(word)[0]))
+	}
+	return string(initials)
+}
+
+// hslToRGB converts hue [0,360), saturation and lightness [0,1] to RGB.
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.RGBA{uint8(math.Round((r + m) * 255)), uint8(math.Round((g + m) * 255)), uint8(math.Round((b + m) * 255)), 255}
+}
+
+// MonogramColor derives a background color from the slug; the same slug
+// always results in the same color, which is dark enough for white text.
+func MonogramColor(slug string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(slug))
+	return hslToRGB(float64(h.Sum32()%360), 0.55, 0.42)
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func monogramFontScale(initials string) float64 {
+	if len([]rune(initials)) > 1 {
+		return 0.42
+	}
+	return 0.5
+}
+
+// MonogramSVG renders the initials on the background color as SVG image.
+func MonogramSVG(initials string, background color.RGBA, size int) []byte {
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[1]d" viewBox="0 0 %[1]d %[1]d">`+
+		`<rect width="%[1]d" height="%[1]d" fill="%[2]s"/>`+
+		`<text x="50%%" y="50%%" dy=".35em" text-anchor="middle" font-family="sans-serif" font-weight="bold" font-size="%[3]d" fill="#ffffff">%[4]s</text>`+
+		`</svg>`,
+		size, hexColor(background), int(float64(size)*monogramFontScale(initials)), html.EscapeString(initials)))
+}
+
+// Monogram renders the initials on the background color as square raster image.
+func Monogram(initials string, background color.RGBA, size int) (image.Image, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	if initials == "" {
+		return img, nil
+	}
+
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("parse font: %w", err)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    float64(size) * monogramFontScale(initials),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create font face: %w", err)
+	}
+	defer face.Close()
+
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(color.White), Face: face}
+	width := drawer.MeasureString(initials)
+	capHeight := face.Metrics().CapHeight
+	drawer.Dot = fixed.Point26_6{
+		X: (fixed.I(size) - width) / 2,
+		Y: (fixed.I(size) + capHeight) / 2,
+	}
+	drawer.DrawString(initials)
+
+	return img, nil
+}
@@ -0,0 +1,63 @@
+package images
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Berlin Run Club", "B"},
+		{"Kreuzberg Coffee Run", "KC"},
+		{"Köln Läufer", "KL"},
+		{"run club", "RC"},
+		{"ölberg-runners 2024", "Ö2"},
+		{"", ""},
+		{"---", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Initials(tt.name)
+			if result != tt.expected {
+				t.Errorf("Initials(%q) = %q, want %q", tt.name, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMonogramColor(t *testing.T) {
+	a := MonogramColor("/berlin/mitte-run")
+	if b := MonogramColor("/berlin/mitte-run"); a != b {
+		t.Errorf("MonogramColor() not deterministic: %v != %v", a, b)
+	}
+	if b := MonogramColor("/koeln/koeln-laeufer"); a == b {
+		t.Errorf("MonogramColor() same color for different slugs: %v", a)
+	}
+	if a.A != 255 {
+		t.Errorf("MonogramColor() alpha = %d, want 255", a.A)
+	}
+}
+
+func TestMonogram(t *testing.T) {
+	background := MonogramColor("/berlin/mitte-run")
+
+	img, err := Monogram("MR", background, 200)
+	if err != nil {
+		t.Fatalf("Monogram() error = %v", err)
+	}
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 200 {
+		t.Errorf("Monogram() size = %v, want 200x200", img.Bounds())
+	}
+	if r, g, b, _ := img.At(0, 0).RGBA(); uint8(r>>8) != background.R || uint8(g>>8) != background.G || uint8(b>>8) != background.B {
+		t.Errorf("Monogram() corner color = %v, want %v", img.At(0, 0), background)
+	}
+
+	svg := string(MonogramSVG("MR", background, 100))
+	if !strings.Contains(svg, ">MR</text>") || !strings.Contains(svg, hexColor(background)) {
+		t.Errorf("MonogramSVG() = %q", svg)
+	}
+}
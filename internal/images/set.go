@@ -0,0 +1,267 @@
+package images
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// Format is an output image format. Formats without a built-in encoder are
+// created by an external command, e.g. "cwebp -quiet -q 80 IN -o OUT", where
+// IN is replaced by a temporary PNG file and OUT by the target file.
+type Format struct {
+	Ext     string
+	Type    string
+	Command string
+}
+
+var (
+	JPEG = Format{Ext: ".jpg", Type: "image/jpeg"}
+	PNG  = Format{Ext: ".png", Type: "image/png"}
+)
+
+func WebP(command string) Format {
+	return Format{Ext: ".webp", Type: "image/webp", Command: command}
+}
+
+func AVIF(command string) Format {
+	return Format{Ext: ".avif", Type: "image/avif", Command: command}
+}
+
+// Available checks whether img can be encoded in the format f: formats with
+// a built-in encoder always, the others only if the command is installed.
+func (f Format) Available() error {
+	if f.Command == "" {
+		return nil
+	}
+	args := strings.Fields(f.Command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("encoder %q not found: %w", args[0], err)
+	}
+	return nil
+}
+
+// Save encodes img in the format f; see the package function Save for the
+// handling of dst.
+func (f Format) Save(img image.Image, dst string) (string, error) {
+	if f.Command == "" {
+		return Save(img, dst)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "images")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	in, err := Save(img, filepath.Join(tmpDir, "in.png"))
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(tmpDir, "out"+f.Ext)
+
+	args := strings.Fields(f.Command)
+	for i, arg := range args {
+		switch arg {
+		case "IN":
+			args[i] = in
+		case "OUT":
+			args[i] = out
+		}
+	}
+	if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("run %q: %w (%s)", f.Command, err, strings.TrimSpace(string(output)))
+	}
+
+	if !strings.Contains(dst, "HASH") {
+		return dst, utils.CopyFile(out, dst)
+	}
+	return utils.CopyHash(out, dst)
+}
+
+// Set is a collection of rendered variants of an image in several formats.
+type Set struct {
+	Alt      string
+	formats  []Format
+	variants []Variant
+	paths    map[string]map[string]string // variant name -> format extension -> path
+	vector   string                       // optional SVG version, preferred for display
+}
+
+func NewSet(alt string) *Set {
+	return &Set{Alt: alt, paths: make(map[string]map[string]string)}
+}
+
+// Add renders img in all given variants and formats, the formats being listed
+// in order of preference. The target filenames are created by replacing "SIZE"
+// in dstPattern by the variant name and "EXT" by the format's extension
+// (without the leading "."). The paths stored in the set are made relative to
+// baseDir (with a leading "/").
+func (s *Set) Add(img image.Image, baseDir string, dstPattern string, variants []Variant, formats []Format) error {
+	for _, f := range formats {
+		known := false
+		for _, other := range s.formats {
+			known = known || other.Ext == f.Ext
+		}
+		if !known {
+			s.formats = append(s.formats, f)
+		}
+	}
+
+	for _, v := range variants {
+		if s.paths[v.Name] == nil {
+			s.variants = append(s.variants, v)
+			s.paths[v.Name] = make(map[string]string)
+		}
+		resized := Resize(img, v)
+		for _, f := range formats {
+			dst := strings.ReplaceAll(strings.ReplaceAll(dstPattern, "SIZE", v.Name), "EXT", strings.TrimPrefix(f.Ext, "."))
+			out, err := f.Save(resized, dst)
+			if err != nil {
+				return fmt.Errorf("variant %s%s: %w", v.Name, f.Ext, err)
+			}
+			rel, err := filepath.Rel(baseDir, out)
+			if err != nil {
+				return err
+			}
+			s.paths[v.Name][f.Ext] = "/" + filepath.ToSlash(rel)
+		}
+	}
+	return nil
+}
+
+// AddSVG stores the SVG image data in dst (see Save for the handling of "HASH")
+// and uses it for display instead of the raster variants.
+func (s *Set) AddSVG(svg []byte, baseDir string, dst string) error {
+	tmpFile, err := os.CreateTemp("", "*.svg")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(svg); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	out := dst
+	if strings.Contains(dst, "HASH") {
+		out, err = utils.CopyHash(tmpFile.Name(), dst)
+	} else {
+		err = utils.CopyFile(tmpFile.Name(), dst)
+	}
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(baseDir, out)
+	if err != nil {
+		return err
+	}
+	s.vector = "/" + filepath.ToSlash(rel)
+	return nil
+}
+
+// Path returns the path of the given variant and format, or "" if it does not exist.
+func (s *Set) Path(variant string, ext string) string {
+	if s == nil {
+		return ""
+	}
+	return s.paths[variant][ext]
+}
+
+// Candidate is an entry of a srcset attribute.
+type Candidate struct {
+	Path    string
+	Density string
+}
+
+type Source struct {
+	Type   string
+	Srcset []Candidate
+}
+
+// Loading modes of the <img> element: images above the fold should be loaded
+// eagerly, all others lazily.
+const (
+	LoadLazy  = "lazy"
+	LoadEager = "eager"
+)
+
+// Picture holds everything needed to render a <picture> element.
+type Picture struct {
+	Alt     string
+	Width   int
+	Height  int
+	Loading string // LoadLazy or LoadEager
+	Src     string
+	Srcset  []Candidate
+	Sources []Source
+}
+
+// variantFor returns the smallest square variant with at least the given size.
+func (s *Set) variantFor(size int, ext string) (Variant, bool) {
+	var best Variant
+	found := false
+	for _, v := range s.variants {
+		if v.Pad || v.Width != v.Height || v.Width < size || s.Path(v.Name, ext) == "" {
+			continue
+		}
+		if !found || v.Width < best.Width {
+			best = v
+			found = true
+		}
+	}
+	return best, found
+}
+
+func (s *Set) candidates(size int, ext string) []Candidate {
+	result := make([]Candidate, 0, 2)
+	v1, found := s.variantFor(size, ext)
+	if !found {
+		return result
+	}
+	result = append(result, Candidate{Path: s.Path(v1.Name, ext), Density: "1x"})
+	if v2, found := s.variantFor(2*size, ext); found && v2.Name != v1.Name {
+		result = append(result, Candidate{Path: s.Path(v2.Name, ext), Density: "2x"})
+	}
+	return result
+}
+
+// Picture returns the <picture> data for displaying the image with the given
+// size (in CSS pixels) and loading mode (LoadLazy or LoadEager): the best
+// matching 1x and 2x variants in all modern formats, with a JPEG fallback.
+func (s *Set) Picture(size int, loading string) Picture {
+	if loading != LoadEager {
+		loading = LoadLazy
+	}
+	if s == nil {
+		return Picture{Width: size, Height: size, Loading: loading}
+	}
+	p := Picture{Alt: s.Alt, Width: size, Height: size, Loading: loading}
+	if s.vector != "" {
+		p.Src = s.vector
+		return p
+	}
+	for _, f := range s.formats {
+		if f.Ext == JPEG.Ext {
+			continue
+		}
+		if srcset := s.candidates(size, f.Ext); len(srcset) > 0 {
+			p.Sources = append(p.Sources, Source{Type: f.Type, Srcset: srcset})
+		}
+	}
+	p.Srcset = s.candidates(size, JPEG.Ext)
+	if len(p.Srcset) > 0 {
+		p.Src = p.Srcset[0].Path
+	}
+	return p
+}
@@ -0,0 +1,102 @@
+package images
+
+import (
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetAdd(t *testing.T) {
+	tmpDir := t.TempDir()
+	img := testImage(400, 300, color.White)
+
+	// "cp" stands in for an external encoder
+	formats := []Format{WebP("cp IN OUT"), JPEG}
+
+	set := NewSet("Logo")
+	if err := set.Add(img, tmpDir, filepath.Join(tmpDir, "club", "img-SIZE.HASH.EXT"), LogoVariants, formats); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	for _, v := range LogoVariants {
+		for _, f := range formats {
+			path := set.Path(v.Name, f.Ext)
+			if !strings.HasPrefix(path, "/club/img-"+v.Name+".") || !strings.HasSuffix(path, f.Ext) {
+				t.Errorf("Path(%s, %s) = %q", v.Name, f.Ext, path)
+			}
+		}
+	}
+
+	loaded, err := Load(filepath.Join(tmpDir, set.Path(Large.Name, JPEG.Ext)))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Bounds().Dx() != 200 || loaded.Bounds().Dy() != 200 {
+		t.Errorf("Load() size = %v, want 200x200", loaded.Bounds())
+	}
+
+	if path := set.Path(Share.Name, JPEG.Ext); path != "" {
+		t.Errorf("Path(share) = %q, want empty", path)
+	}
+}
+
+func TestSetAddFailingEncoder(t *testing.T) {
+	tmpDir := t.TempDir()
+	set := NewSet("Logo")
+	err := set.Add(testImage(10, 10, color.White), tmpDir, filepath.Join(tmpDir, "img-SIZE.EXT"), LogoVariants, []Format{AVIF("false")})
+	if err == nil {
+		t.Error("Add() with failing encoder: expected error")
+	}
+}
+
+func TestFormatAvailable(t *testing.T) {
+	if err := JPEG.Available(); err != nil {
+		t.Errorf("JPEG.Available() error = %v", err)
+	}
+	if err := WebP("no-such-webp-encoder -q 80 IN -o OUT").Available(); err == nil {
+		t.Error("Available() with missing encoder: expected error")
+	}
+}
+
+func TestSetPicture(t *testing.T) {
+	set := NewSet("Logo")
+	set.formats = []Format{AVIF(""), WebP(""), JPEG}
+	set.variants = []Variant{Small, Medium, Share}
+	set.paths = map[string]map[string]string{
+		"50":  {".jpg": "/a-50.jpg", ".webp": "/a-50.webp"},
+		"100": {".jpg": "/a-100.jpg", ".webp": "/a-100.webp", ".avif": "/a-100.avif"},
+	}
+
+	p := set.Picture(50, LoadLazy)
+	if p.Width != 50 || p.Height != 50 || p.Alt != "Logo" || p.Loading != LoadLazy {
+		t.Errorf("Picture(50) = %+v", p)
+	}
+	if p.Src != "/a-50.jpg" {
+		t.Errorf("Picture(50).Src = %q, want %q", p.Src, "/a-50.jpg")
+	}
+	if len(p.Srcset) != 2 || p.Srcset[1] != (Candidate{"/a-100.jpg", "2x"}) {
+		t.Errorf("Picture(50).Srcset = %+v", p.Srcset)
+	}
+	if len(p.Sources) != 2 || p.Sources[0].Type != "image/avif" || p.Sources[1].Type != "image/webp" {
+		t.Fatalf("Picture(50).Sources = %+v", p.Sources)
+	}
+	if len(p.Sources[0].Srcset) != 1 || p.Sources[0].Srcset[0] != (Candidate{"/a-100.avif", "1x"}) {
+		t.Errorf("Picture(50).Sources[0].Srcset = %+v", p.Sources[0].Srcset)
+	}
+
+	// 45px is displayed using the 50px and 100px variants
+	if p := set.Picture(45, LoadLazy); p.Src != "/a-50.jpg" || len(p.Srcset) != 2 {
+		t.Errorf("Picture(45) = %+v", p)
+	}
+
+	// no larger variant for 2x
+	if p := set.Picture(100, LoadEager); p.Src != "/a-100.jpg" || len(p.Srcset) != 1 || p.Loading != LoadEager {
+		t.Errorf("Picture(100) = %+v", p)
+	}
+
+	var empty *Set
+	if p := empty.Picture(100, ""); p.Src != "" || p.Width != 100 || p.Loading != LoadLazy {
+		t.Errorf("nil Picture(100) = %+v", p)
+	}
+}
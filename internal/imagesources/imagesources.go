@@ -0,0 +1,141 @@
+package imagesources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// ErrNotApplicable is returned by providers that cannot handle a club, e.g.
+// because the club has no Instagram profile.
+var ErrNotApplicable = errors.New("not applicable")
+
+// Club holds the club data relevant for finding an image.
+type Club struct {
+	Slug      string // unique name of the club, e.g. "berlin/berlin-run-club"
+	ImageURL  string // manual override: URL or local file
+	Instagram string
+	Strava    string
+	Website   string
+}
+
+// Image is a found image: the local file and where it came from.
+type Image struct {
+	Source string
+	URL    string
+	File   string
+}
+
+type Provider interface {
+	Name() string
+	// Find returns the club's image; cached downloads are reused unless refresh is set.
+	Find(club Club, refresh bool) (Image, error)
+}
+
+// DefaultOrder is the provider priority used for clubs without a custom order.
+var DefaultOrder = []string{"manual", "instagram", "strava", "directory", "website"}
+
+// Options configure the providers.
+type Options struct {
+	Download      func(url string, targetPath string) error // plain download
+	DownloadAgent func(url string, targetPath string) error // download with browser user agent
+	CacheDir      string                                    // download cache
+	ImageDir      string                                    // manually collected images
+}
+
+// New creates the providers with the given names.
+func New(names []string, options Options) ([]Provider, error) {
+	providers := make([]Provider, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "manual":
+			providers = append(providers, &Manual{options.Download, options.CacheDir})
+		case "instagram":
+			providers = append(providers, &Instagram{options.Download, options.CacheDir})
+		case "strava":
+			providers = append(providers, &Strava{options.DownloadAgent, options.CacheDir})
+		case "website":
+			providers = append(providers, &Website{options.DownloadAgent, options.CacheDir})
+		case "directory":
+			providers = append(providers, &Directory{options.ImageDir})
+		default:
+			return nil, fmt.Errorf("unknown image provider: %q", name)
+		}
+	}
+	return providers, nil
+}
+
+// Order returns the providers in the given order. An empty order results in
+// all providers; an order with unknown names, e.g. a typo in the sheet,
+// results in an error and the providers in DefaultOrder.
+func Order(providers []Provider, order []string) ([]Provider, error) {
+	if len(order) == 0 {
+		return providers, nil
+	}
+	result := make([]Provider, 0, len(order))
+	unknown := make([]string, 0)
+	for _, name := range order {
+		found := false
+		for _, provider := range providers {
+			if provider.Name() == name {
+				result = append(result, provider)
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		fallback, _ := Order(providers, DefaultOrder)
+		return fallback, fmt.Errorf("unknown image providers %q, using default order", unknown)
+	}
+	return result, nil
+}
+
+// Find tries the providers in order and returns the first found image.
+func Find(providers []Provider, club Club, refresh bool) (Image, error) {
+	errs := make([]error, 0)
+	for _, provider := range providers {
+		img, err := provider.Find(club, refresh)
+		if err == nil {
+			return img, nil
+		}
+		if !errors.Is(err, ErrNotApplicable) {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+		}
+	}
+	if len(errs) == 0 {
+		return Image{}, fmt.Errorf("no image source available")
+	}
+	return Image{}, errors.Join(errs...)
+}
+
+// cachedDownload downloads url to target, unless target already exists and refresh is not set.
+func cachedDownload(download func(url string, targetPath string) error, url string, target string, refresh bool) error {
+	if !refresh && utils.FileExists(target) {
+		return nil
+	}
+	return download(url, target)
+}
+
+var reOgImage = regexp.MustCompile(`<meta\s+property="og:image"\s+content="([^"]+)"`)
+
+// extractOgImage returns the og:image URL of the HTML file.
+func extractOgImage(htmlFile string) (string, error) {
+	htmlBytes, err := os.ReadFile(htmlFile)
+	if err != nil {
+		return "", fmt.Errorf("reading HTML file: %w", err)
+	}
+
+	matches := reOgImage.FindStringSubmatch(string(htmlBytes))
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not find og:image in %s", htmlFile)
+	}
+
+	return strings.ReplaceAll(matches[1], "&amp;", "&"), nil
+}
@@ -0,0 +1,230 @@
+package imagesources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// fakeDownload serves the fixtures in testdata instead of accessing the network.
+type fakeDownload struct {
+	files     map[string]string // URL => fixture
+	requested []string
+}
+
+func (d *fakeDownload) download(url string, targetPath string) error {
+	d.requested = append(d.requested, url)
+	fixture, found := d.files[url]
+	if !found {
+		return fmt.Errorf("unexpected URL: %s", url)
+	}
+	if err := utils.MakeDir(filepath.Dir(targetPath)); err != nil {
+		return err
+	}
+	return utils.CopyFile(filepath.Join("testdata", fixture), targetPath)
+}
+
+func newProviders(t *testing.T, names []string, files map[string]string) ([]Provider, *fakeDownload, string) {
+	t.Helper()
+	d := &fakeDownload{files: files}
+	cacheDir := t.TempDir()
+	providers, err := New(names, Options{
+		Download:      d.download,
+		DownloadAgent: d.download,
+		CacheDir:      cacheDir,
+		ImageDir:      "testdata/club-images",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return providers, d, cacheDir
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := New([]string{"instagram", "myspace"}, Options{}); err == nil {
+		t.Error("New() with unknown provider: expected error")
+	}
+}
+
+func TestInstagram(t *testing.T) {
+	imageURL := "https://scontent.cdninstagram.com/v/t51.2885-19/profile.jpg?stp=dst-jpg_s100x100&oh=abc"
+	providers, d, cacheDir := newProviders(t, []string{"instagram"}, map[string]string{
+		"https://www.instagram.com/examplerunclub/": "instagram.html",
+		imageURL: "image.jpg",
+	})
+	club := Club{Slug: "berlin/example-run-club", Instagram: "https://www.instagram.com/examplerunclub/"}
+
+	img, err := providers[0].Find(club, false)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	expected := Image{"instagram", imageURL, filepath.Join(cacheDir, "instagram", "examplerunclub", "image.jpg")}
+	if img != expected {
+		t.Errorf("Find() = %+v, want %+v", img, expected)
+	}
+
+	// cached files are reused, unless refresh is set
+	if _, err := providers[0].Find(club, false); err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(d.requested) != 2 {
+		t.Errorf("cached Find() requested %v", d.requested)
+	}
+	if _, err := providers[0].Find(club, true); err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(d.requested) != 4 {
+		t.Errorf("refreshing Find() requested %v", d.requested)
+	}
+
+	if _, err := providers[0].Find(Club{Slug: "berlin/other"}, false); !errors.Is(err, ErrNotApplicable) {
+		t.Errorf("Find() without Instagram error = %v, want ErrNotApplicable", err)
+	}
+}
+
+func TestStrava(t *testing.T) {
+	imageURL := "https://dgalywyr863hv.cloudfront.net/pictures/clubs/123456/large.jpg"
+	providers, _, cacheDir := newProviders(t, []string{"strava"}, map[string]string{
+		"https://www.strava.com/clubs/123456": "strava.html",
+		imageURL:                              "image.jpg",
+	})
+
+	img, err := providers[0].Find(Club{Slug: "berlin/example-run-club", Strava: "https://www.strava.com/clubs/123456"}, false)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	expected := Image{"strava", imageURL, filepath.Join(cacheDir, "strava", "123456", "image.jpg")}
+	if img != expected {
+		t.Errorf("Find() = %+v, want %+v", img, expected)
+	}
+
+	if _, err := providers[0].Find(Club{Strava: "https://www.strava.com/athletes/1"}, false); !errors.Is(err, ErrNotApplicable) {
+		t.Errorf("Find() with non-club URL error = %v, want ErrNotApplicable", err)
+	}
+}
+
+func TestWebsite(t *testing.T) {
+	for _, test := range []struct {
+		fixture  string
+		imageURL string
+	}{
+		{"website-ogimage.html", "https://example.com/assets/share.jpg"},
+		{"website-icon.html", "https://example.com/club/img/apple-touch-icon.png?v=2"},
+	} {
+		providers, _, _ := newProviders(t, []string{"website"}, map[string]string{
+			"https://example.com/club/": test.fixture,
+			test.imageURL:               "image.jpg",
+		})
+		img, err := providers[0].Find(Club{Slug: "berlin/example-run-club", Website: "https://example.com/club/"}, false)
+		if err != nil {
+			t.Errorf("%s: Find() error = %v", test.fixture, err)
+			continue
+		}
+		if img.Source != "website" || img.URL != test.imageURL {
+			t.Errorf("%s: Find() = %+v, want URL %s", test.fixture, img, test.imageURL)
+		}
+	}
+
+	providers, _, _ := newProviders(t, []string{"website"}, map[string]string{
+		"https://example.com/": "website-none.html",
+	})
+	if _, err := providers[0].Find(Club{Slug: "berlin/example-run-club", Website: "https://example.com/"}, false); err == nil || errors.Is(err, ErrNotApplicable) {
+		t.Errorf("Find() without usable image error = %v, want failure", err)
+	}
+}
+
+func TestManual(t *testing.T) {
+	providers, _, cacheDir := newProviders(t, []string{"manual"}, map[string]string{
+		"https://example.com/logo.jpg": "image.jpg",
+	})
+
+	img, err := providers[0].Find(Club{Slug: "berlin/example-run-club", ImageURL: "https://example.com/logo.jpg"}, false)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	expected := Image{"manual", "https://example.com/logo.jpg", filepath.Join(cacheDir, "manual", utils.SanitizeName("berlin/example-run-club"), "image")}
+	if img != expected {
+		t.Errorf("Find() = %+v, want %+v", img, expected)
+	}
+
+	local := filepath.Join("testdata", "image.jpg")
+	if img, err := providers[0].Find(Club{ImageURL: local}, false); err != nil || img.File != local {
+		t.Errorf("Find() with local file = %+v, %v", img, err)
+	}
+	if _, err := providers[0].Find(Club{ImageURL: "testdata/missing.jpg"}, false); err == nil {
+		t.Error("Find() with missing local file: expected error")
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := utils.MakeDir(filepath.Join(dir, "berlin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "berlin", "example-run-club.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider := &Directory{dir}
+
+	img, err := provider.Find(Club{Slug: "berlin/example-run-club"}, false)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if !strings.HasSuffix(img.File, "example-run-club.png") {
+		t.Errorf("Find() = %+v", img)
+	}
+	if _, err := provider.Find(Club{Slug: "berlin/other"}, false); !errors.Is(err, ErrNotApplicable) {
+		t.Errorf("Find() of missing file error = %v, want ErrNotApplicable", err)
+	}
+}
+
+func TestOrderAndFind(t *testing.T) {
+	providers, _, _ := newProviders(t, DefaultOrder, map[string]string{
+		"https://www.strava.com/clubs/123456":                                  "strava.html",
+		"https://dgalywyr863hv.cloudfront.net/pictures/clubs/123456/large.jpg": "image.jpg",
+	})
+
+	names := func(providers []Provider) []string {
+		result := make([]string, 0, len(providers))
+		for _, p := range providers {
+			result = append(result, p.Name())
+		}
+		return result
+	}
+	if got, err := Order(providers, nil); err != nil || !reflect.DeepEqual(names(got), DefaultOrder) {
+		t.Errorf("Order(nil) = %v, %v", names(got), err)
+	}
+	if got, err := Order(providers, []string{"website", "strava"}); err != nil || !reflect.DeepEqual(names(got), []string{"website", "strava"}) {
+		t.Errorf("Order() = %v, %v", names(got), err)
+	}
+	// a typo falls back to the default order
+	if got, err := Order(providers, []string{"website", "insta"}); err == nil || !strings.Contains(err.Error(), "insta") || !reflect.DeepEqual(names(got), DefaultOrder) {
+		t.Errorf("Order() with unknown name = %v, %v", names(got), err)
+	}
+
+	// the failing Instagram provider is skipped
+	club := Club{Slug: "berlin/example-run-club", Instagram: "https://www.instagram.com/examplerunclub/", Strava: "https://www.strava.com/clubs/123456"}
+	img, err := Find(providers, club, false)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if img.Source != "strava" {
+		t.Errorf("Find() = %+v, want strava image", img)
+	}
+
+	// only the Instagram provider is allowed
+	instagram, _ := Order(providers, []string{"instagram"})
+	if _, err := Find(instagram, club, false); err == nil || !strings.Contains(err.Error(), "instagram") {
+		t.Errorf("Find() error = %v, want instagram error", err)
+	}
+
+	if _, err := Find(providers, Club{Slug: "berlin/other"}, false); err == nil {
+		t.Error("Find() without any source: expected error")
+	}
+}
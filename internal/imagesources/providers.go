@@ -0,0 +1,201 @@
+package imagesources
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// Manual uses the image URL (or local file) entered manually for the club.
+type Manual struct {
+	download func(url string, targetPath string) error
+	cacheDir string
+}
+
+func (p *Manual) Name() string {
+	return "manual"
+}
+
+func (p *Manual) Find(club Club, refresh bool) (Image, error) {
+	if club.ImageURL == "" {
+		return Image{}, ErrNotApplicable
+	}
+
+	if !strings.HasPrefix(club.ImageURL, "http://") && !strings.HasPrefix(club.ImageURL, "https://") {
+		if !utils.FileExists(club.ImageURL) {
+			return Image{}, fmt.Errorf("image file does not exist: %s", club.ImageURL)
+		}
+		return Image{p.Name(), club.ImageURL, club.ImageURL}, nil
+	}
+
+	target := filepath.Join(p.cacheDir, "manual", utils.SanitizeName(club.Slug), "image")
+	if err := cachedDownload(p.download, club.ImageURL, target, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading image: %w", err)
+	}
+	return Image{p.Name(), club.ImageURL, target}, nil
+}
+
+var reInstagramProfile = regexp.MustCompile(`https?://(www\.)?instagram\.com/([^/?]+)/*`)
+
+// Instagram uses the og:image of the club's Instagram profile page.
+type Instagram struct {
+	download func(url string, targetPath string) error
+	cacheDir string
+}
+
+func (p *Instagram) Name() string {
+	return "instagram"
+}
+
+func (p *Instagram) Find(club Club, refresh bool) (Image, error) {
+	matches := reInstagramProfile.FindStringSubmatch(club.Instagram)
+	if matches == nil {
+		return Image{}, ErrNotApplicable
+	}
+	profileName := matches[2]
+
+	targetProfileHtml := filepath.Join(p.cacheDir, "instagram", profileName, "html")
+	if err := cachedDownload(p.download, club.Instagram, targetProfileHtml, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading Instagram profile: %w", err)
+	}
+
+	imageURL, err := extractOgImage(targetProfileHtml)
+	if err != nil {
+		return Image{}, fmt.Errorf("extracting Instagram profile image URL: %w", err)
+	}
+
+	targetProfileImage := filepath.Join(p.cacheDir, "instagram", profileName, "image.jpg")
+	if err := cachedDownload(p.download, imageURL, targetProfileImage, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading Instagram profile image: %w", err)
+	}
+
+	return Image{p.Name(), imageURL, targetProfileImage}, nil
+}
+
+var reStravaClub = regexp.MustCompile(`^https?://www\.strava\.com/clubs/([^/]+)/?$`)
+
+// Strava uses the og:image of the club's Strava club page.
+type Strava struct {
+	download func(url string, targetPath string) error
+	cacheDir string
+}
+
+func (p *Strava) Name() string {
+	return "strava"
+}
+
+func (p *Strava) Find(club Club, refresh bool) (Image, error) {
+	matches := reStravaClub.FindStringSubmatch(club.Strava)
+	if matches == nil {
+		return Image{}, ErrNotApplicable
+	}
+	stravaClubId := matches[1]
+
+	targetStravaHtml := filepath.Join(p.cacheDir, "strava", stravaClubId, "html")
+	if err := cachedDownload(p.download, club.Strava, targetStravaHtml, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading Strava club HTML: %w", err)
+	}
+
+	imageURL, err := extractOgImage(targetStravaHtml)
+	if err != nil {
+		return Image{}, fmt.Errorf("extracting Strava club image URL: %w", err)
+	}
+
+	targetStravaImage := filepath.Join(p.cacheDir, "strava", stravaClubId, "image.jpg")
+	if err := cachedDownload(p.download, imageURL, targetStravaImage, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading Strava club image: %w", err)
+	}
+
+	return Image{p.Name(), imageURL, targetStravaImage}, nil
+}
+
+var reIconLink = regexp.MustCompile(`<link\s+[^>]*rel="(?:apple-touch-icon|icon|shortcut icon)"[^>]*>`)
+var reHref = regexp.MustCompile(`href="([^"]+)"`)
+
+// extractIcon returns the first icon of the HTML file in a decodable format.
+func extractIcon(htmlFile string) (string, error) {
+	htmlBytes, err := os.ReadFile(htmlFile)
+	if err != nil {
+		return "", fmt.Errorf("reading HTML file: %w", err)
+	}
+
+	for _, link := range reIconLink.FindAllString(string(htmlBytes), -1) {
+		matches := reHref.FindStringSubmatch(link)
+		if matches == nil {
+			continue
+		}
+		href := strings.ReplaceAll(matches[1], "&amp;", "&")
+		ext := strings.ToLower(path.Ext(strings.SplitN(href, "?", 2)[0]))
+		if ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".webp" {
+			return href, nil
+		}
+	}
+	return "", fmt.Errorf("could not find icon in %s", htmlFile)
+}
+
+// Website uses the og:image of the club's website, or its icon.
+type Website struct {
+	download func(url string, targetPath string) error
+	cacheDir string
+}
+
+func (p *Website) Name() string {
+	return "website"
+}
+
+func (p *Website) Find(club Club, refresh bool) (Image, error) {
+	base, err := url.Parse(club.Website)
+	if club.Website == "" || err != nil || base.Host == "" {
+		return Image{}, ErrNotApplicable
+	}
+
+	cacheDir := filepath.Join(p.cacheDir, "website", utils.SanitizeName(club.Slug))
+	targetHtml := filepath.Join(cacheDir, "html")
+	if err := cachedDownload(p.download, club.Website, targetHtml, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading website: %w", err)
+	}
+
+	imageURL, err := extractOgImage(targetHtml)
+	if err != nil {
+		if imageURL, err = extractIcon(targetHtml); err != nil {
+			return Image{}, fmt.Errorf("extracting website image URL: %w", err)
+		}
+	}
+	ref, err := url.Parse(imageURL)
+	if err != nil {
+		return Image{}, fmt.Errorf("invalid image URL %q: %w", imageURL, err)
+	}
+	imageURL = base.ResolveReference(ref).String()
+
+	targetImage := filepath.Join(cacheDir, "image")
+	if err := cachedDownload(p.download, imageURL, targetImage, refresh); err != nil {
+		return Image{}, fmt.Errorf("downloading website image: %w", err)
+	}
+
+	return Image{p.Name(), imageURL, targetImage}, nil
+}
+
+// Directory uses manually collected images, stored as "DIR/CITY/CLUB.EXT".
+type Directory struct {
+	dir string
+}
+
+func (p *Directory) Name() string {
+	return "directory"
+}
+
+func (p *Directory) Find(club Club, refresh bool) (Image, error) {
+	base := filepath.Join(p.dir, filepath.FromSlash(club.Slug))
+	for _, ext := range []string{".jpg", ".jpeg", ".png", ".webp"} {
+		if utils.FileExists(base + ext) {
+			return Image{p.Name(), base + ext, base + ext}, nil
+		}
+	}
+	return Image{}, ErrNotApplicable
+}
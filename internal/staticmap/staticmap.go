@@ -0,0 +1,125 @@
+// Package staticmap renders small map images from slippy map tiles, e.g. as
+// lightweight placeholders for interactive maps.
+package staticmap
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+var (
+	background  = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	markerFill  = color.RGBA{0xd6, 0x33, 0x33, 0xff}
+	markerFrame = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// Map describes the rendered image: its size in pixels, the zoom level and the
+// source of the tiles.
+type Map struct {
+	Width  int
+	Height int
+	Zoom   int
+	Tiles  TileSource
+}
+
+// project returns the position of p in world pixel coordinates (Web Mercator)
+// at the given zoom level.
+func project(p utils.LatLon, zoom int) (float64, float64) {
+	size := float64(TileSize) * math.Exp2(float64(zoom))
+	lat := math.Max(-85.0511, math.Min(85.0511, p.Lat))
+	x := (p.Lon + 180.0) / 360.0 * size
+	sinLat := math.Sin(lat * math.Pi / 180.0)
+	y := (0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)) * size
+	return x, y
+}
+
+// Render draws the map centered at center with a pin for each of the markers.
+// Tiles outside of the world (beyond the poles) are left blank; longitudes
+// wrap around.
+func (m Map) Render(center utils.LatLon, markers ...utils.LatLon) (image.Image, error) {
+	if m.Width <= 0 || m.Height <= 0 {
+		return nil, fmt.Errorf("invalid map size %dx%d", m.Width, m.Height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	cx, cy := project(center, m.Zoom)
+	// world pixel coordinates of the image's top left corner
+	left := int(math.Floor(cx)) - m.Width/2
+	top := int(math.Floor(cy)) - m.Height/2
+
+	numTiles := 1 << m.Zoom
+	for ty := floorDiv(top, TileSize); ty*TileSize < top+m.Height; ty++ {
+		if ty < 0 || ty >= numTiles {
+			continue
+		}
+		for tx := floorDiv(left, TileSize); tx*TileSize < left+m.Width; tx++ {
+			tile, err := m.Tiles.Tile(m.Zoom, ((tx%numTiles)+numTiles)%numTiles, ty)
+			if err != nil {
+				return nil, fmt.Errorf("tile %d/%d/%d: %w", m.Zoom, tx, ty, err)
+			}
+			dst := image.Rect(tx*TileSize-left, ty*TileSize-top, (tx+1)*TileSize-left, (ty+1)*TileSize-top)
+			draw.Draw(img, dst, tile, tile.Bounds().Min, draw.Src)
+		}
+	}
+
+	for _, marker := range markers {
+		x, y := project(marker, m.Zoom)
+		drawPin(img, x-float64(left), y-float64(top))
+	}
+	return img, nil
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((b - 1 - a) / b)
+	}
+	return a / b
+}
+
+// drawPin draws a map pin whose tip is at (x, y): a circle with a pointed
+// bottom, filled red with a white frame.
+func drawPin(img *image.RGBA, x, y float64) {
+	const radius = 9.0
+	const frame = 2.0
+	const height = 26.0
+	headX, headY := x, y-height+radius+frame
+
+	// inside reports whether (px, py) is within the pin shape grown by d
+	inside := func(px, py, d float64) bool {
+		dx, dy := px-headX, py-headY
+		if dx*dx+dy*dy <= (radius+d)*(radius+d) {
+			return true
+		}
+		// triangle from the circle's sides down to the tip
+		if py < headY || py > y+d {
+			return false
+		}
+		halfWidth := (radius + d) * (y + d - py) / (y + d - headY)
+		return math.Abs(px-x) <= halfWidth
+	}
+
+	bounds := image.Rect(int(x-radius-frame-1), int(y-height-1), int(x+radius+frame+2), int(y+frame+2)).Intersect(img.Bounds())
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			fx, fy := float64(px)+0.5, float64(py)+0.5
+			switch {
+			case inside(fx, fy, 0):
+				dx, dy := fx-headX, fy-headY
+				if dx*dx+dy*dy <= 9 {
+					// white dot in the pin's head
+					img.SetRGBA(px, py, markerFrame)
+				} else {
+					img.SetRGBA(px, py, markerFill)
+				}
+			case inside(fx, fy, frame):
+				img.SetRGBA(px, py, markerFrame)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package staticmap
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// tileColor gives each test tile a distinct color
+func tileColor(x, y int) color.RGBA {
+	return color.RGBA{uint8(40 * x), uint8(40 * y), 0x80, 0xff}
+}
+
+func tilePNG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, TileSize, TileSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeTiles creates all tiles of the zoom level in dir
+func writeTiles(t *testing.T, dir string, zoom int) {
+	t.Helper()
+	n := 1 << zoom
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			file := filepath.Join(dir, strconv.Itoa(zoom), strconv.Itoa(x), strconv.Itoa(y)+".png")
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, tilePNG(t, tileColor(x, y)), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	for _, tc := range []struct {
+		p    utils.LatLon
+		zoom int
+		x, y float64
+	}{
+		{utils.LatLon{Lat: 0, Lon: 0}, 0, 128, 128},
+		{utils.LatLon{Lat: 0, Lon: -180}, 1, 0, 256},
+		{utils.LatLon{Lat: 85.0511, Lon: 180}, 2, 1024, 0},
+	} {
+		x, y := project(tc.p, tc.zoom)
+		if math.Abs(x-tc.x) > 0.01 || math.Abs(y-tc.y) > 0.01 {
+			t.Errorf("project(%v, %d) = %f, %f, want %f, %f", tc.p, tc.zoom, x, y, tc.x, tc.y)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	writeTiles(t, dir, 2)
+
+	// the center of tile 2/2/1
+	center := utils.LatLon{Lat: 40.97990, Lon: 45}
+	m := Map{Width: 300, Height: 300, Zoom: 2, Tiles: DirTiles(dir)}
+	img, err := m.Render(center, center)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if img.Bounds().Dx() != 300 || img.Bounds().Dy() != 300 {
+		t.Fatalf("Render() size = %v", img.Bounds())
+	}
+
+	// corners are covered by the tiles around 2/2/1
+	for _, tc := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{0, 0, tileColor(1, 0)},
+		{299, 0, tileColor(3, 0)},
+		{0, 299, tileColor(1, 2)},
+		{299, 299, tileColor(3, 2)},
+		{150, 200, tileColor(2, 1)},
+	} {
+		if c := color.RGBAModel.Convert(img.At(tc.x, tc.y)); c != tc.expected {
+			t.Errorf("At(%d, %d) = %v, want %v", tc.x, tc.y, c, tc.expected)
+		}
+	}
+
+	// pin above the marker position
+	if c := color.RGBAModel.Convert(img.At(150, 140)); c != markerFill {
+		t.Errorf("marker: At(150, 140) = %v, want %v", c, markerFill)
+	}
+
+	// beyond the poles, there are no tiles
+	img, err = Map{Width: 100, Height: 600, Zoom: 0, Tiles: DirTiles(dir)}.Render(utils.LatLon{})
+	if err == nil {
+		t.Fatal("Render() with missing tiles: expected error")
+	}
+	writeTiles(t, dir, 0)
+	img, err = Map{Width: 100, Height: 600, Zoom: 0, Tiles: DirTiles(dir)}.Render(utils.LatLon{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if c := color.RGBAModel.Convert(img.At(50, 5)); c != background {
+		t.Errorf("At(50, 5) = %v, want background", c)
+	}
+
+	if _, err := (Map{Zoom: 1, Tiles: DirTiles(dir)}).Render(center); err == nil {
+		t.Error("Render() with empty size: expected error")
+	}
+}
+
+func TestHTTPTiles(t *testing.T) {
+	var requests atomic.Int32
+	tile := tilePNG(t, tileColor(1, 1))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/3/4/5.png" || r.Header.Get("User-Agent") != "test-agent" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(tile)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	tiles := NewHTTPTiles(server.URL+"/{z}/{x}/{y}.png", cacheDir, "test-agent")
+	for i := 0; i < 2; i++ {
+		img, err := tiles.Tile(3, 4, 5)
+		if err != nil {
+			t.Fatalf("Tile() error = %v", err)
+		}
+		if c := color.RGBAModel.Convert(img.At(0, 0)); c != tileColor(1, 1) {
+			t.Errorf("Tile() color = %v", c)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests = %d, want 1 (second tile from cache)", n)
+	}
+	if !utils.FileExists(tiles.cacheFile(3, 4, 5)) {
+		t.Errorf("tile not cached in %s", tiles.cacheFile(3, 4, 5))
+	}
+
+	if _, err := tiles.Tile(3, 4, 6); err == nil {
+		t.Error("Tile() of missing tile: expected error")
+	}
+}
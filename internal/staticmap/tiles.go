@@ -0,0 +1,154 @@
+package staticmap
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flopp/socialrunclubs-de/internal/utils"
+)
+
+// TileSize is the width and height of a map tile in pixels.
+const TileSize = 256
+
+// TileSource provides the map tiles in the usual z/x/y (slippy map) scheme.
+type TileSource interface {
+	Tile(z, x, y int) (image.Image, error)
+}
+
+// DirTiles reads tiles from a local directory with the layout {z}/{x}/{y}.png,
+// e.g. a pre-rendered extract or test fixtures.
+type DirTiles string
+
+func (d DirTiles) Tile(z, x, y int) (image.Image, error) {
+	return loadTile(filepath.Join(string(d), strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y)+".png"))
+}
+
+// HTTPTiles fetches tiles from a tile server and keeps them in an on-disk
+// cache, so repeated builds do not hit the server again.
+type HTTPTiles struct {
+	URL       string // URL template with {z}, {x}, {y} and optional {s} (subdomain "a")
+	CacheDir  string
+	UserAgent string
+	client    *http.Client
+}
+
+func NewHTTPTiles(urlTemplate, cacheDir, userAgent string) *HTTPTiles {
+	return &HTTPTiles{
+		URL:       urlTemplate,
+		CacheDir:  cacheDir,
+		UserAgent: userAgent,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *HTTPTiles) url(z, x, y int) string {
+	return strings.NewReplacer(
+		"{s}", "a",
+		"{z}", strconv.Itoa(z),
+		"{x}", strconv.Itoa(x),
+		"{y}", strconv.Itoa(y),
+	).Replace(t.URL)
+}
+
+// cacheFile returns the cache location of the tile; tiles of different servers
+// are kept apart by the server's host name.
+func (t *HTTPTiles) cacheFile(z, x, y int) string {
+	host := "tiles"
+	if u, err := url.Parse(t.URL); err == nil && u.Host != "" {
+		host = strings.NewReplacer("{", "", "}", "", ":", "_").Replace(u.Host)
+	}
+	return filepath.Join(t.CacheDir, host, strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y)+".png")
+}
+
+func (t *HTTPTiles) Tile(z, x, y int) (image.Image, error) {
+	cacheFile := t.cacheFile(z, x, y)
+	if utils.FileExists(cacheFile) {
+		return loadTile(cacheFile)
+	}
+
+	data, err := t.fetch(t.url(z, x, y))
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode tile %d/%d/%d: %w", z, x, y, err)
+	}
+	if err := utils.WriteFileAtomic(cacheFile, data, 0644); err != nil {
+		return nil, fmt.Errorf("cache tile %d/%d/%d: %w", z, x, y, err)
+	}
+	return img, nil
+}
+
+func (t *HTTPTiles) fetch(tileURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", tileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// tile servers like tile.openstreetmap.org require an identifying user agent
+	if t.UserAgent != "" {
+		req.Header.Set("User-Agent", t.UserAgent)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", tileURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: non-ok http status: %v", tileURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", tileURL, err)
+	}
+	return data, nil
+}
+
+func loadTile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// MemoryCache keeps the tiles of a source in memory, as neighbouring maps
+// mostly share their tiles. It is not safe for concurrent use.
+type MemoryCache struct {
+	source TileSource
+	tiles  map[[3]int]image.Image
+}
+
+func NewMemoryCache(source TileSource) *MemoryCache {
+	return &MemoryCache{source: source, tiles: make(map[[3]int]image.Image)}
+}
+
+func (c *MemoryCache) Tile(z, x, y int) (image.Image, error) {
+	key := [3]int{z, x, y}
+	if tile, found := c.tiles[key]; found {
+		return tile, nil
+	}
+	tile, err := c.source.Tile(z, x, y)
+	if err != nil {
+		return nil, err
+	}
+	c.tiles[key] = tile
+	return tile, nil
+}
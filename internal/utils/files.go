@@ -63,3 +63,58 @@ func CopyFile(src, dst string) error {
 func CopyHash(src, dst string) (string, error) {
 	return filehash.Copy(src, dst, "HASH")
 }
+
+// WriteHash writes data to dst, replacing "HASH" in dst by the content hash
+// (see CopyHash); it returns the resulting file name.
+func WriteHash(dst string, data []byte) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "writehash")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmp := filepath.Join(tmpDir, filepath.Base(dst))
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+	return CopyHash(tmp, dst)
+}
+
+// FileHash returns the content hash of the file, as used by CopyHash.
+func FileHash(path string) (string, error) {
+	return filehash.Compute(path)
+}
+
+// WriteFileAtomic writes data to a temporary file and renames it to fileName,
+// so readers (and crashes) never see a partially written file.
+func WriteFileAtomic(fileName string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(fileName)
+	if err := MakeDir(dir); err != nil {
+		return fmt.Errorf("create dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -125,3 +126,47 @@ func TestCopyFile(t *testing.T) {
 		}
 	})
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sub", "file.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(fileName, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFileAtomic() error = %v", err)
+		}
+		buf, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(buf) != content {
+			t.Errorf("content = %q, want %q", buf, content)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory contains %d files, want 1", len(entries))
+	}
+}
+
+func TestWriteHash(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := WriteHash(filepath.Join(dir, "sub", "index.HASH.js"), []byte("first"))
+	if err != nil {
+		t.Fatalf("WriteHash() error = %v", err)
+	}
+	second, err := WriteHash(filepath.Join(dir, "sub", "index.HASH.js"), []byte("second"))
+	if err != nil {
+		t.Fatalf("WriteHash() error = %v", err)
+	}
+	if first == second || strings.Contains(first, "HASH") || filepath.Dir(first) != filepath.Join(dir, "sub") {
+		t.Errorf("WriteHash() = %q, %q", first, second)
+	}
+	if buf, err := os.ReadFile(second); err != nil || string(buf) != "second" {
+		t.Errorf("content = %q, %v", buf, err)
+	}
+}
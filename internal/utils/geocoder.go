@@ -1,93 +1,410 @@
 package utils
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
-
-	"github.com/codingsince1985/geo-golang"
-	"github.com/codingsince1985/geo-golang/openstreetmap"
 )
 
-type CachingGeocoder struct {
-	download    func(url string, targetPath string) error
-	cacheFile   string
-	rateLimiter <-chan time.Time
-	geocoder    geo.Geocoder
+// Region is the administrative region of a place.
+type Region struct {
+	State    string // Bundesland
+	District string // Landkreis; empty for district-free cities
 }
 
-func NewCachingGeocoder(download func(url string, targetPath string) error, cacheFile string) *CachingGeocoder {
-	return &CachingGeocoder{download, cacheFile, time.Tick(time.Second), openstreetmap.Geocoder()}
+// Geocoding is a geocoder result including its provenance.
+type Geocoding struct {
+	LatLon
+	Provider   string
+	Confidence float64 // 0 (vague) ... 1 (exact match)
+	Region
 }
 
-type geocoderCacheData map[string]LatLon
+// Geocoder determines the coordinates of a German city, or the region of a
+// location (reverse geocoding).
+type Geocoder interface {
+	Lookup(city string) (Geocoding, error)
+	Reverse(coords LatLon) (Geocoding, error)
+}
 
-func (g *CachingGeocoder) lookupOSM(address string) (LatLon, error) {
-	<-g.rateLimiter
+// GeocoderChain tries the geocoders in order and returns the first result.
+type GeocoderChain []Geocoder
 
-	location, err := g.geocoder.Geocode(address)
-	if err != nil {
-		return LatLon{}, err
+func (c GeocoderChain) Lookup(city string) (Geocoding, error) {
+	if len(c) == 0 {
+		return Geocoding{}, fmt.Errorf("no geocoder available")
+	}
+	errs := make([]error, 0, len(c))
+	for _, geocoder := range c {
+		result, err := geocoder.Lookup(city)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, err)
 	}
-	if location == nil {
-		return LatLon{}, fmt.Errorf("cannot get coordinates of '%s'", address)
+	return Geocoding{}, errors.Join(errs...)
+}
+
+func (c GeocoderChain) Reverse(coords LatLon) (Geocoding, error) {
+	if len(c) == 0 {
+		return Geocoding{}, fmt.Errorf("no geocoder available")
+	}
+	errs := make([]error, 0, len(c))
+	for _, geocoder := range c {
+		result, err := geocoder.Reverse(coords)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, err)
 	}
+	return Geocoding{}, errors.Join(errs...)
+}
+
+// SplitGeocoder answers lookups and reverse lookups with different geocoders.
+type SplitGeocoder struct {
+	LookupGeocoder  Geocoder
+	ReverseGeocoder Geocoder
+}
 
-	return LatLon{location.Lat, location.Lng}, nil
+func (s SplitGeocoder) Lookup(city string) (Geocoding, error) {
+	return s.LookupGeocoder.Lookup(city)
 }
 
-func loadCache(filePath string) (geocoderCacheData, error) {
-	data := make(geocoderCacheData)
-	if FileExists(filePath) {
-		if buf, err := os.ReadFile(filePath); err != nil {
-			return data, err
-		} else {
-			if err := json.Unmarshal(buf, &data); err != nil {
-				return data, err
+func (s SplitGeocoder) Reverse(coords LatLon) (Geocoding, error) {
+	return s.ReverseGeocoder.Reverse(coords)
+}
+
+// GazetteerEntry is a municipality of the gazetteer.
+type GazetteerEntry struct {
+	Name string
+	AGS  string // Amtlicher Gemeindeschlüssel; empty if unknown
+	LatLon
+	Region
+	Population int     // 0 if unknown
+	Area       float64 // km²; 0 if unknown
+}
+
+// Gazetteer is an offline geocoder based on a list of municipalities.
+type Gazetteer map[string][]GazetteerEntry
+
+// gazetteerMaxDistance is the max. distance (km) of a location to the center of the
+// nearest municipality for reverse lookups.
+const gazetteerMaxDistance = 10.0
+
+// gazetteerBorderMargin is the min. difference (km) between the distances to
+// the nearest municipality and to the nearest municipality of another state
+// for reverse lookups; closer to a state border, the nearest municipality
+// may well lie in the wrong state.
+const gazetteerBorderMargin = 5.0
+
+// LoadGazetteer loads a CSV file with the columns "name", "lat" and "lon" and
+// the optional columns "state" and "district"; additional columns are ignored.
+func LoadGazetteer(filePath string) (Gazetteer, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	colIdx := make(map[string]int)
+	for i, col := range header {
+		colIdx[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	for _, col := range []string{"name", "lat", "lon"} {
+		if _, found := colIdx[col]; !found {
+			return nil, fmt.Errorf("missing column: %s", col)
+		}
+	}
+
+	gazetteer := make(Gazetteer)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(row) < len(header) {
+			return nil, fmt.Errorf("line %d: expected %d columns, got %d", line, len(header), len(row))
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(row[colIdx["lat"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid lat: %w", line, err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(row[colIdx["lon"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid lon: %w", line, err)
+		}
+		entry := GazetteerEntry{Name: strings.TrimSpace(row[colIdx["name"]]), LatLon: LatLon{lat, lon}}
+		if col, found := colIdx["state"]; found {
+			entry.State = strings.TrimSpace(row[col])
+		}
+		if col, found := colIdx["district"]; found {
+			entry.District = strings.TrimSpace(row[col])
+		}
+		if col, found := colIdx["ags"]; found {
+			entry.AGS = strings.TrimSpace(row[col])
+		}
+		if col, found := colIdx["population"]; found && strings.TrimSpace(row[col]) != "" {
+			if entry.Population, err = strconv.Atoi(strings.TrimSpace(row[col])); err != nil {
+				return nil, fmt.Errorf("line %d: invalid population: %w", line, err)
 			}
 		}
+		if col, found := colIdx["area"]; found && strings.TrimSpace(row[col]) != "" {
+			if entry.Area, err = strconv.ParseFloat(strings.TrimSpace(row[col]), 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid area: %w", line, err)
+			}
+		}
+		key := gazetteerKey(row[colIdx["name"]])
+		gazetteer[key] = append(gazetteer[key], entry)
 	}
-	return data, nil
+	return gazetteer, nil
 }
 
-func saveCache(filePath string, data geocoderCacheData) error {
-	if buf, err := json.Marshal(data); err != nil {
+// Entries returns all municipalities with the given name.
+func (g Gazetteer) Entries(name string) []GazetteerEntry {
+	return g[gazetteerKey(name)]
+}
+
+// WriteGazetteer writes the entries in the format read by LoadGazetteer.
+func WriteGazetteer(filePath string, entries []GazetteerEntry) error {
+	var buf strings.Builder
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{"name", "ags", "state", "district", "lat", "lon", "population", "area"}); err != nil {
 		return err
-	} else {
-		if err := MakeDir(filepath.Dir(filePath)); err != nil {
-			return err
+	}
+	for _, entry := range entries {
+		population, area := "", ""
+		if entry.Population > 0 {
+			population = strconv.Itoa(entry.Population)
+		}
+		if entry.Area > 0 {
+			area = strconv.FormatFloat(entry.Area, 'f', 2, 64)
 		}
-		if err := os.WriteFile(filePath, buf, 0644); err != nil {
+		row := []string{
+			entry.Name, entry.AGS, entry.State, entry.District,
+			strconv.FormatFloat(entry.Lat, 'f', 5, 64), strconv.FormatFloat(entry.Lon, 'f', 5, 64),
+			population, area,
+		}
+		if err := writer.Write(row); err != nil {
 			return err
 		}
 	}
-	return nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return WriteFileAtomic(filePath, []byte(buf.String()), 0644)
 }
 
-func (g *CachingGeocoder) Lookup(city string) (LatLon, error) {
-	data, err := loadCache(g.cacheFile)
-	if err != nil {
-		return LatLon{}, err
+func gazetteerKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (g Gazetteer) Lookup(city string) (Geocoding, error) {
+	entries := g[gazetteerKey(city)]
+	switch len(entries) {
+	case 0:
+		return Geocoding{}, fmt.Errorf("gazetteer: unknown city '%s'", city)
+	case 1:
+		return Geocoding{entries[0].LatLon, "gazetteer", 1.0, entries[0].Region}, nil
+	default:
+		return Geocoding{}, fmt.Errorf("gazetteer: ambiguous city '%s' (%d matches)", city, len(entries))
+	}
+}
+
+// Reverse returns the region of the nearest municipality; locations near a
+// state border are rejected, as the gazetteer has no municipality boundaries.
+func (g Gazetteer) Reverse(coords LatLon) (Geocoding, error) {
+	var nearest *GazetteerEntry
+	nearestDistance := 0.0
+	distanceByState := make(map[string]float64) // distance to the nearest municipality of each state
+	for _, entries := range g {
+		for i := range entries {
+			distance := Distance(coords, entries[i].LatLon)
+			if nearest == nil || distance < nearestDistance {
+				nearest = &entries[i]
+				nearestDistance = distance
+			}
+			if state := entries[i].State; state != "" {
+				if d, found := distanceByState[state]; !found || distance < d {
+					distanceByState[state] = distance
+				}
+			}
+		}
+	}
+	if nearest == nil || nearestDistance > gazetteerMaxDistance || nearest.State == "" {
+		return Geocoding{}, fmt.Errorf("gazetteer: no municipality near %.4f,%.4f", coords.Lat, coords.Lon)
+	}
+	for state, distance := range distanceByState {
+		if state != nearest.State && distance < nearestDistance+gazetteerBorderMargin {
+			return Geocoding{}, fmt.Errorf("gazetteer: %.4f,%.4f is near the border of %s and %s", coords.Lat, coords.Lon, nearest.State, state)
+		}
 	}
+	// the farther away, the less likely the location belongs to the same region
+	return Geocoding{coords, "gazetteer", 1.0 - nearestDistance/gazetteerMaxDistance, nearest.Region}, nil
+}
+
+// NominatimURL is the default endpoint of the online geocoder.
+const NominatimURL = "https://nominatim.openstreetmap.org/"
 
-	if coords, found := data[city]; found {
-		return coords, nil
+// Nominatim is an online geocoder using the Nominatim search API.
+type Nominatim struct {
+	endpoint    string
+	rateLimiter <-chan time.Time
+	client      *http.Client
+}
+
+// NewNominatim creates a geocoder querying the Nominatim instance at endpoint
+// (NominatimURL if empty); requests are limited to one per second.
+func NewNominatim(endpoint string) *Nominatim {
+	if endpoint == "" {
+		endpoint = NominatimURL
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
 	}
+	return &Nominatim{endpoint, time.Tick(time.Second), &http.Client{Timeout: 30 * time.Second}}
+}
 
-	log.Printf("geocoder: looking up '%s'", city)
-	coords, err := g.lookupOSM(fmt.Sprintf("%s, Germany", city))
+type nominatimResult struct {
+	Lat        string  `json:"lat"`
+	Lon        string  `json:"lon"`
+	Importance float64 `json:"importance"`
+	Address    struct {
+		State  string `json:"state"`
+		County string `json:"county"`
+	} `json:"address"`
+	Error string `json:"error"`
+}
+
+func (r nominatimResult) geocoding() (Geocoding, error) {
+	lat, err := strconv.ParseFloat(r.Lat, 64)
+	if err != nil {
+		return Geocoding{}, fmt.Errorf("nominatim: invalid lat: %w", err)
+	}
+	lon, err := strconv.ParseFloat(r.Lon, 64)
 	if err != nil {
-		return LatLon{}, err
+		return Geocoding{}, fmt.Errorf("nominatim: invalid lon: %w", err)
+	}
+	return Geocoding{LatLon{lat, lon}, "nominatim", r.Importance, Region{r.Address.State, r.Address.County}}, nil
+}
+
+// get fetches the API path (with query parameters) and decodes the JSON response into result.
+func (n *Nominatim) get(path string, result any) error {
+	<-n.rateLimiter
+
+	req, err := http.NewRequest("GET", n.endpoint+path+"&format=jsonv2&addressdetails=1&accept-language=de", nil)
+	if err != nil {
+		return fmt.Errorf("nominatim: %w", err)
+	}
+	// the Nominatim usage policy requires an identifying user agent
+	req.Header.Set("User-Agent", "socialrunclubs.de")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nominatim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim: non-ok http status: %v", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("nominatim: decoding response: %w", err)
+	}
+	return nil
+}
+
+func (n *Nominatim) Lookup(city string) (Geocoding, error) {
+	query := fmt.Sprintf("%s, Germany", city)
+	log.Printf("geocoder: looking up '%s'", query)
+
+	var results []nominatimResult
+	if err := n.get("search?limit=1&q="+url.QueryEscape(query), &results); err != nil {
+		return Geocoding{}, err
+	}
+	if len(results) == 0 {
+		return Geocoding{}, fmt.Errorf("nominatim: cannot get coordinates of '%s'", query)
 	}
+	return results[0].geocoding()
+}
 
-	data[city] = coords
+func (n *Nominatim) Reverse(coords LatLon) (Geocoding, error) {
+	log.Printf("geocoder: looking up region of %.4f,%.4f", coords.Lat, coords.Lon)
 
-	if err := saveCache(g.cacheFile, data); err != nil {
-		return coords, err
+	// zoom 10 = city level
+	var result nominatimResult
+	if err := n.get(fmt.Sprintf("reverse?zoom=10&lat=%f&lon=%f", coords.Lat, coords.Lon), &result); err != nil {
+		return Geocoding{}, err
+	}
+	if result.Error != "" {
+		return Geocoding{}, fmt.Errorf("nominatim: cannot get region of %.4f,%.4f: %s", coords.Lat, coords.Lon, result.Error)
 	}
+	if result.Address.State == "" {
+		return Geocoding{}, fmt.Errorf("nominatim: cannot get region of %.4f,%.4f", coords.Lat, coords.Lon)
+	}
+	return result.geocoding()
+}
+
+// CachingGeocoder caches the results of another geocoder.
+type CachingGeocoder struct {
+	cache    *GeocoderCache
+	geocoder Geocoder
+}
+
+func NewCachingGeocoder(cache *GeocoderCache, geocoder Geocoder) *CachingGeocoder {
+	return &CachingGeocoder{cache, geocoder}
+}
+
+// cached returns the cached result for query, or fetches (and caches) a new one.
+func (g *CachingGeocoder) cached(query string, fetch func() (Geocoding, error)) (Geocoding, error) {
+	entry, fresh := g.cache.Get(query)
+	if entry != nil && fresh {
+		return entry.Geocoding, nil
+	}
+
+	result, err := fetch()
+	if err != nil {
+		// an outdated result is better than none
+		if entry != nil {
+			log.Printf("geocoder: refreshing '%s' failed, using cached result: %v", query, err)
+			return entry.Geocoding, nil
+		}
+		return Geocoding{}, err
+	}
+
+	if err := g.cache.Put(query, result); err != nil {
+		return result, fmt.Errorf("saving geocoder cache: %w", err)
+	}
+	return result, nil
+}
+
+func (g *CachingGeocoder) Lookup(city string) (Geocoding, error) {
+	return g.cached(city, func() (Geocoding, error) {
+		return g.geocoder.Lookup(city)
+	})
+}
 
-	return coords, nil
+func (g *CachingGeocoder) Reverse(coords LatLon) (Geocoding, error) {
+	// reverse lookups share the cache, their queries are the coordinates
+	query := fmt.Sprintf("@%.5f,%.5f", coords.Lat, coords.Lon)
+	return g.cached(query, func() (Geocoding, error) {
+		return g.geocoder.Reverse(coords)
+	})
 }
@@ -2,28 +2,35 @@ package utils
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
-
-	geo "github.com/codingsince1985/geo-golang"
 )
 
 type stubGeocoder struct {
-	geocode func(address string) (*geo.Location, error)
+	lookup  func(city string) (Geocoding, error)
+	reverse func(coords LatLon) (Geocoding, error)
 	calls   int
 }
 
-func (s *stubGeocoder) Geocode(address string) (*geo.Location, error) {
+func (s *stubGeocoder) Lookup(city string) (Geocoding, error) {
 	s.calls++
-	if s.geocode == nil {
-		return nil, fmt.Errorf("unexpected geocode call for %q", address)
+	if s.lookup == nil {
+		return Geocoding{}, fmt.Errorf("unexpected lookup call for %q", city)
 	}
-	return s.geocode(address)
+	return s.lookup(city)
 }
 
-func (s *stubGeocoder) ReverseGeocode(lat, lng float64) (*geo.Address, error) {
-	return nil, fmt.Errorf("unexpected reverse geocode call for %f,%f", lat, lng)
+func (s *stubGeocoder) Reverse(coords LatLon) (Geocoding, error) {
+	s.calls++
+	if s.reverse == nil {
+		return Geocoding{}, fmt.Errorf("unexpected reverse call for %v", coords)
+	}
+	return s.reverse(coords)
 }
 
 func TestCachingGeocoderLookupReturnsCachedCoordinates(t *testing.T) {
@@ -31,52 +38,47 @@ func TestCachingGeocoderLookupReturnsCachedCoordinates(t *testing.T) {
 	cacheFile := filepath.Join(tmpDir, "geocoder-cache.json")
 	expected := LatLon{Lat: 52.52, Lon: 13.405}
 
-	if err := saveCache(cacheFile, geocoderCacheData{"Berlin": expected}); err != nil {
-		t.Fatalf("saveCache() error = %v", err)
+	// old cache format: plain coordinates
+	if err := os.WriteFile(cacheFile, []byte(`{"Berlin": {"Lat": 52.52, "Lon": 13.405}}`), 0644); err != nil {
+		t.Fatal(err)
 	}
-
-	geocoder := &stubGeocoder{}
-	cache := &CachingGeocoder{
-		cacheFile: cacheFile,
-		geocoder:  geocoder,
+	cache, err := LoadGeocoderCache(cacheFile, 0)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
 	}
 
-	coords, err := cache.Lookup("Berlin")
+	geocoder := &stubGeocoder{}
+	coords, err := NewCachingGeocoder(cache, geocoder).Lookup("Berlin")
 	if err != nil {
 		t.Fatalf("Lookup() error = %v", err)
 	}
-	if coords != expected {
+	if coords.LatLon != expected {
 		t.Fatalf("Lookup() = %+v, want %+v", coords, expected)
 	}
 	if geocoder.calls != 0 {
-		t.Fatalf("Geocode() calls = %d, want 0", geocoder.calls)
+		t.Fatalf("Lookup() calls = %d, want 0", geocoder.calls)
 	}
 }
 
 func TestCachingGeocoderLookupCachesFetchedCoordinates(t *testing.T) {
 	tmpDir := t.TempDir()
 	cacheFile := filepath.Join(tmpDir, "geocoder-cache.json")
-	expected := LatLon{Lat: 48.1351, Lon: 11.5820}
-
-	rateLimiter := make(chan time.Time, 1)
-	rateLimiter <- time.Now()
+	expected := Geocoding{LatLon{Lat: 48.1351, Lon: 11.5820}, "stub", 0.8, Region{}}
 
 	geocoder := &stubGeocoder{
-		geocode: func(address string) (*geo.Location, error) {
-			if address != "Munich, Germany" {
-				return nil, fmt.Errorf("Geocode() address = %q, want %q", address, "Munich, Germany")
+		lookup: func(city string) (Geocoding, error) {
+			if city != "Munich" {
+				return Geocoding{}, fmt.Errorf("Lookup() city = %q, want %q", city, "Munich")
 			}
-			return &geo.Location{Lat: expected.Lat, Lng: expected.Lon}, nil
+			return expected, nil
 		},
 	}
 
-	cache := &CachingGeocoder{
-		cacheFile:   cacheFile,
-		rateLimiter: rateLimiter,
-		geocoder:    geocoder,
+	cache, err := LoadGeocoderCache(cacheFile, 0)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
 	}
-
-	coords, err := cache.Lookup("Munich")
+	coords, err := NewCachingGeocoder(cache, geocoder).Lookup("Munich")
 	if err != nil {
 		t.Fatalf("Lookup() error = %v", err)
 	}
@@ -84,14 +86,218 @@ func TestCachingGeocoderLookupCachesFetchedCoordinates(t *testing.T) {
 		t.Fatalf("Lookup() = %+v, want %+v", coords, expected)
 	}
 	if geocoder.calls != 1 {
-		t.Fatalf("Geocode() calls = %d, want 1", geocoder.calls)
+		t.Fatalf("Lookup() calls = %d, want 1", geocoder.calls)
+	}
+
+	reloaded, err := LoadGeocoderCache(cacheFile, 0)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
+	}
+	entry, fresh := reloaded.Get("Munich")
+	if entry == nil || !fresh {
+		t.Fatalf("Get() = %+v, %v", entry, fresh)
+	}
+	if entry.Query != "Munich" || entry.Geocoding != expected || entry.Time.IsZero() {
+		t.Fatalf("cached entry = %+v, want %+v", entry, expected)
+	}
+}
+
+func TestCachingGeocoderRefreshesStaleEntries(t *testing.T) {
+	cache, err := LoadGeocoderCache(filepath.Join(t.TempDir(), "geocoder.json"), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	old := Geocoding{LatLon{Lat: 1, Lon: 1}, "stub", 0.5, Region{}}
+	if err := cache.Put("Berlin", old); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	updated := Geocoding{LatLon{Lat: 52.52, Lon: 13.405}, "stub", 0.9, Region{}}
+	geocoder := &stubGeocoder{lookup: func(city string) (Geocoding, error) { return updated, nil }}
+	caching := NewCachingGeocoder(cache, geocoder)
+
+	if result, _ := caching.Lookup("Berlin"); result != old || geocoder.calls != 0 {
+		t.Errorf("Lookup() of fresh entry = %+v (calls %d), want %+v", result, geocoder.calls, old)
+	}
+
+	now = now.Add(48 * time.Hour)
+	if result, _ := caching.Lookup("Berlin"); result != updated || geocoder.calls != 1 {
+		t.Errorf("Lookup() of stale entry = %+v (calls %d), want %+v", result, geocoder.calls, updated)
+	}
+
+	// failed refreshs fall back to the stale entry
+	now = now.Add(48 * time.Hour)
+	geocoder.lookup = nil
+	if result, err := caching.Lookup("Berlin"); err != nil || result != updated {
+		t.Errorf("Lookup() with failing refresh = %+v, %v, want %+v", result, err, updated)
+	}
+}
+
+func TestGeocoderCacheConcurrentPut(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "geocoder.json")
+	cache, err := LoadGeocoderCache(cacheFile, 0)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := cache.Put(fmt.Sprintf("city-%d", i), Geocoding{LatLon{float64(i), 0}, "stub", 1, Region{}}); err != nil {
+				t.Errorf("Put() error = %v", err)
+			}
+		}(i)
 	}
+	wg.Wait()
 
-	data, err := loadCache(cacheFile)
+	reloaded, err := LoadGeocoderCache(cacheFile, 0)
 	if err != nil {
-		t.Fatalf("loadCache() error = %v", err)
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
+	}
+	if n := len(reloaded.Entries()); n != 20 {
+		t.Errorf("Entries() = %d, want 20", n)
+	}
+	if matches, _ := filepath.Glob(cacheFile + ".*"); len(matches) != 0 {
+		t.Errorf("left over temp files: %v", matches)
+	}
+}
+
+func TestGeocoderCacheInvalidate(t *testing.T) {
+	cache, err := LoadGeocoderCache(filepath.Join(t.TempDir(), "geocoder.json"), 0)
+	if err != nil {
+		t.Fatalf("LoadGeocoderCache() error = %v", err)
+	}
+	for _, city := range []string{"Berlin", "Hamburg", "Köln"} {
+		provider := "nominatim"
+		if city == "Köln" {
+			provider = "gazetteer"
+		}
+		if err := cache.Put(city, Geocoding{LatLon{1, 1}, provider, 1, Region{}}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	removed, err := cache.Invalidate(func(entry GeocoderCacheEntry) bool { return entry.Provider == "nominatim" })
+	if err != nil || removed != 2 {
+		t.Fatalf("Invalidate() = %d, %v, want 2", removed, err)
+	}
+	entries := cache.Entries()
+	if len(entries) != 1 || entries[0].Query != "Köln" {
+		t.Errorf("Entries() = %+v", entries)
+	}
+}
+
+func TestNominatimCustomEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search" && r.URL.Query().Get("q") == "Leipzig, Germany":
+			fmt.Fprint(w, `[{"lat": "51.34", "lon": "12.37", "display_name": "Leipzig", "importance": 0.75, "address": {"city": "Leipzig", "state": "Sachsen"}}]`)
+		case r.URL.Path == "/reverse" && r.URL.Query().Get("lat") == "50.900000":
+			fmt.Fprint(w, `{"lat": "50.9", "lon": "6.9", "address": {"town": "Hürth", "county": "Rhein-Erft-Kreis", "state": "Nordrhein-Westfalen"}}`)
+		case r.URL.Path == "/reverse":
+			fmt.Fprint(w, `{"error": "Unable to geocode"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	nominatim := NewNominatim(server.URL)
+	result, err := nominatim.Lookup("Leipzig")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if expected := (Geocoding{LatLon{Lat: 51.34, Lon: 12.37}, "nominatim", 0.75, Region{State: "Sachsen"}}); result != expected {
+		t.Fatalf("Lookup() = %+v, want %+v", result, expected)
+	}
+
+	result, err = nominatim.Reverse(LatLon{Lat: 50.9, Lon: 6.9})
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	if expected := (Region{"Nordrhein-Westfalen", "Rhein-Erft-Kreis"}); result.Region != expected {
+		t.Fatalf("Reverse() = %+v, want %+v", result.Region, expected)
+	}
+	if _, err := nominatim.Reverse(LatLon{Lat: 0, Lon: 0}); err == nil {
+		t.Error("Reverse() of unknown location: expected error")
+	}
+}
+
+func TestGazetteer(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "gemeinden.csv")
+	content := "name,state,lat,lon,population\n" +
+		"Berlin,Berlin,52.52,13.405,3755000\n" +
+		"Neustadt,Hessen,50.85,9.11,\n" +
+		"Neustadt,Sachsen,51.02,14.21,\n"
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gazetteer, err := LoadGazetteer(fileName)
+	if err != nil {
+		t.Fatalf("LoadGazetteer() error = %v", err)
+	}
+
+	coords, err := gazetteer.Lookup("berlin")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if expected := (Geocoding{LatLon{Lat: 52.52, Lon: 13.405}, "gazetteer", 1, Region{State: "Berlin"}}); coords != expected {
+		t.Fatalf("Lookup() = %+v, want %+v", coords, expected)
+	}
+	if entry := gazetteer["berlin"][0]; entry.Name != "Berlin" || entry.Population != 3755000 {
+		t.Errorf("gazetteer entry = %+v", entry)
+	}
+	if _, err := gazetteer.Lookup("Neustadt"); err == nil {
+		t.Error("Lookup() of ambiguous city: expected error")
+	}
+	if _, err := gazetteer.Lookup("Atlantis"); err == nil {
+		t.Error("Lookup() of unknown city: expected error")
+	}
+
+	// reverse lookups use the nearest municipality
+	region, err := gazetteer.Reverse(LatLon{Lat: 51.03, Lon: 14.25})
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	if region.State != "Sachsen" || region.Confidence <= 0 || region.Confidence >= 1 {
+		t.Errorf("Reverse() = %+v, want state Sachsen", region)
+	}
+	if _, err := gazetteer.Reverse(LatLon{Lat: 48.0, Lon: 11.0}); err == nil {
+		t.Error("Reverse() of remote location: expected error")
+	}
+}
+
+func TestGazetteerReverseNearStateBorder(t *testing.T) {
+	gazetteer := Gazetteer{
+		"ulm":     {{Name: "Ulm", LatLon: LatLon{Lat: 48.398, Lon: 9.992}, Region: Region{State: "Baden-Württemberg"}}},
+		"neu-ulm": {{Name: "Neu-Ulm", LatLon: LatLon{Lat: 48.392, Lon: 10.011}, Region: Region{State: "Bayern", District: "Neu-Ulm"}}},
+		"senden":  {{Name: "Senden", LatLon: LatLon{Lat: 48.325, Lon: 10.045}, Region: Region{State: "Bayern", District: "Neu-Ulm"}}},
+	}
+	if region, err := gazetteer.Reverse(LatLon{Lat: 48.395, Lon: 10.005}); err == nil {
+		t.Errorf("Reverse() near state border = %+v, expected error", region)
+	}
+	region, err := gazetteer.Reverse(LatLon{Lat: 48.30, Lon: 10.06})
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	if region.State != "Bayern" {
+		t.Errorf("Reverse() = %+v, want state Bayern", region)
+	}
+}
+
+func TestSplitGeocoder(t *testing.T) {
+	lookup := &stubGeocoder{lookup: func(city string) (Geocoding, error) { return Geocoding{Provider: "lookup"}, nil }}
+	reverse := &stubGeocoder{reverse: func(coords LatLon) (Geocoding, error) { return Geocoding{Provider: "reverse"}, nil }}
+	geocoder := SplitGeocoder{LookupGeocoder: lookup, ReverseGeocoder: reverse}
+	if result, err := geocoder.Lookup("Berlin"); err != nil || result.Provider != "lookup" {
+		t.Errorf("Lookup() = %+v, %v", result, err)
 	}
-	if cached := data["Munich"]; cached != expected {
-		t.Fatalf("cached coordinates = %+v, want %+v", cached, expected)
+	if result, err := geocoder.Reverse(LatLon{Lat: 52.5, Lon: 13.4}); err != nil || result.Provider != "reverse" {
+		t.Errorf("Reverse() = %+v, %v", result, err)
 	}
 }
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GeocoderCacheEntry is a cached geocoder result.
+type GeocoderCacheEntry struct {
+	Query string
+	Geocoding
+	Time time.Time // time of the lookup
+}
+
+// GeocoderCache is a persistent cache of geocoder results, safe for concurrent use.
+// The cache file is loaded once and rewritten atomically on every change.
+type GeocoderCache struct {
+	mutex    sync.Mutex
+	fileName string
+	maxAge   time.Duration
+	entries  map[string]*GeocoderCacheEntry
+	now      func() time.Time
+}
+
+// LoadGeocoderCache loads the cache file (if it exists). Entries older than
+// maxAge are considered stale; a maxAge of 0 disables expiration.
+func LoadGeocoderCache(fileName string, maxAge time.Duration) (*GeocoderCache, error) {
+	cache := &GeocoderCache{
+		fileName: fileName,
+		maxAge:   maxAge,
+		entries:  make(map[string]*GeocoderCacheEntry),
+		now:      time.Now,
+	}
+	if !FileExists(fileName) {
+		return cache, nil
+	}
+
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	// old cache files map queries to plain coordinates, which are read as
+	// entries without provider and time
+	if err := json.Unmarshal(buf, &cache.entries); err != nil {
+		return nil, err
+	}
+	for query, entry := range cache.entries {
+		entry.Query = query
+	}
+	return cache, nil
+}
+
+func (c *GeocoderCache) isStale(entry *GeocoderCacheEntry) bool {
+	return c.maxAge > 0 && c.now().Sub(entry.Time) > c.maxAge
+}
+
+// Get returns a copy of the cached entry for query (nil if there is none)
+// and whether it is still fresh.
+func (c *GeocoderCache) Get(query string) (*GeocoderCacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, found := c.entries[query]
+	if !found {
+		return nil, false
+	}
+	result := *entry
+	return &result, !c.isStale(entry)
+}
+
+// Put adds or replaces the entry for query and saves the cache.
+func (c *GeocoderCache) Put(query string, result Geocoding) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[query] = &GeocoderCacheEntry{query, result, c.now().UTC()}
+	return c.save()
+}
+
+// Entries returns copies of all entries sorted by query.
+func (c *GeocoderCache) Entries() []GeocoderCacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entries := make([]GeocoderCacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Query < entries[j].Query
+	})
+	return entries
+}
+
+// IsStale checks whether the entry is older than the cache's max age.
+func (c *GeocoderCache) IsStale(entry GeocoderCacheEntry) bool {
+	return c.isStale(&entry)
+}
+
+// Invalidate removes all entries matching the predicate, saves the cache and
+// returns the number of removed entries.
+func (c *GeocoderCache) Invalidate(match func(entry GeocoderCacheEntry) bool) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for query, entry := range c.entries {
+		if match(*entry) {
+			delete(c.entries, query)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, c.save()
+}
+
+func (c *GeocoderCache) save() error {
+	buf, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(c.fileName, buf, 0644)
+}
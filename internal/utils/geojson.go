@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Area is a named (multi-)polygon, e.g. a neighbourhood of a city.
+type Area struct {
+	Name     string
+	Polygons [][][]LatLon // polygons, each consisting of an outer ring and optional holes
+}
+
+type geoJSONFeatureCollection struct {
+	Features []struct {
+		Properties map[string]any `json:"properties"`
+		Geometry   struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+func toRings(coordinates [][][2]float64) [][]LatLon {
+	rings := make([][]LatLon, 0, len(coordinates))
+	for _, ring := range coordinates {
+		points := make([]LatLon, 0, len(ring))
+		for _, p := range ring {
+			// GeoJSON positions are [lon, lat]
+			points = append(points, LatLon{Lat: p[1], Lon: p[0]})
+		}
+		rings = append(rings, points)
+	}
+	return rings
+}
+
+// LoadAreas loads the Polygon and MultiPolygon features of a GeoJSON file; the
+// area names are taken from the given feature property.
+func LoadAreas(fileName string, nameProperty string) ([]Area, error) {
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(buf, &collection); err != nil {
+		return nil, fmt.Errorf("parsing GeoJSON: %w", err)
+	}
+
+	areas := make([]Area, 0, len(collection.Features))
+	for index, feature := range collection.Features {
+		name, ok := feature.Properties[nameProperty].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("feature %d: missing property %q", index, nameProperty)
+		}
+		area := Area{Name: name}
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var coordinates [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", index, err)
+			}
+			area.Polygons = append(area.Polygons, toRings(coordinates))
+		case "MultiPolygon":
+			var coordinates [][][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", index, err)
+			}
+			for _, polygon := range coordinates {
+				area.Polygons = append(area.Polygons, toRings(polygon))
+			}
+		default:
+			// points, lines etc. cannot contain locations
+			continue
+		}
+		areas = append(areas, area)
+	}
+	return areas, nil
+}
+
+// ringContains checks whether the point is inside the ring (ray casting).
+func ringContains(ring []LatLon, p LatLon) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) && p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Contains checks whether the point is inside the area (and not inside a hole).
+func (a Area) Contains(p LatLon) bool {
+	for _, polygon := range a.Polygons {
+		if len(polygon) == 0 || !ringContains(polygon[0], p) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, p) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// FindArea returns the first area containing the point, or nil.
+func FindArea(areas []Area, p LatLon) *Area {
+	for i := range areas {
+		if areas[i].Contains(p) {
+			return &areas[i]
+		}
+	}
+	return nil
+}
+
+// Point is a location with arbitrary GeoJSON feature properties.
+type Point struct {
+	LatLon
+	Properties map[string]any
+}
+
+// PointsToGeoJSON creates a GeoJSON feature collection of the points.
+func PointsToGeoJSON(points []Point) ([]byte, error) {
+	type geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	}
+	type feature struct {
+		Type       string         `json:"type"`
+		Properties map[string]any `json:"properties"`
+		Geometry   geometry       `json:"geometry"`
+	}
+	collection := struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}{"FeatureCollection", make([]feature, 0, len(points))}
+	for _, p := range points {
+		properties := p.Properties
+		if properties == nil {
+			properties = make(map[string]any)
+		}
+		// GeoJSON positions are [lon, lat]
+		collection.Features = append(collection.Features, feature{"Feature", properties, geometry{"Point", [2]float64{p.Lon, p.Lat}}})
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"name": "Mitte"},
+      "geometry": {"type": "Polygon", "coordinates": [
+        [[13.0, 52.0], [14.0, 52.0], [14.0, 53.0], [13.0, 53.0], [13.0, 52.0]],
+        [[13.4, 52.4], [13.6, 52.4], [13.6, 52.6], [13.4, 52.6], [13.4, 52.4]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "Inseln"},
+      "geometry": {"type": "MultiPolygon", "coordinates": [
+        [[[10.0, 50.0], [11.0, 50.0], [11.0, 51.0], [10.0, 50.0]]],
+        [[[13.45, 52.45], [13.55, 52.45], [13.55, 52.55], [13.45, 52.55], [13.45, 52.45]]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "Punkt"},
+      "geometry": {"type": "Point", "coordinates": [13.5, 52.5]}
+    }
+  ]
+}`
+
+func TestLoadAreasAndFindArea(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "areas.geojson")
+	if err := os.WriteFile(fileName, []byte(testGeoJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	areas, err := LoadAreas(fileName, "name")
+	if err != nil {
+		t.Fatalf("LoadAreas() error = %v", err)
+	}
+	if len(areas) != 2 {
+		t.Fatalf("LoadAreas() = %d areas, want 2", len(areas))
+	}
+
+	for _, test := range []struct {
+		p        LatLon
+		expected string
+	}{
+		{LatLon{Lat: 52.2, Lon: 13.2}, "Mitte"},
+		{LatLon{Lat: 52.42, Lon: 13.42}, ""}, // in the hole of "Mitte"
+		{LatLon{Lat: 52.5, Lon: 13.5}, "Inseln"},
+		{LatLon{Lat: 50.2, Lon: 10.8}, "Inseln"},
+		{LatLon{Lat: 50.8, Lon: 10.2}, ""},
+		{LatLon{Lat: 48.0, Lon: 11.0}, ""},
+	} {
+		name := ""
+		if area := FindArea(areas, test.p); area != nil {
+			name = area.Name
+		}
+		if name != test.expected {
+			t.Errorf("FindArea(%v) = %q, want %q", test.p, name, test.expected)
+		}
+	}
+
+	if _, err := LoadAreas(fileName, "bezirk"); err == nil {
+		t.Error("LoadAreas() with missing name property: expected error")
+	}
+}
+
+func TestPointsToGeoJSON(t *testing.T) {
+	buf, err := PointsToGeoJSON([]Point{
+		{LatLon{Lat: 52.52, Lon: 13.405}, map[string]any{"name": "Berlin"}},
+		{LatLon{Lat: 53.55, Lon: 9.99}, nil},
+	})
+	if err != nil {
+		t.Fatalf("PointsToGeoJSON() error = %v", err)
+	}
+
+	var collection struct {
+		Type     string
+		Features []struct {
+			Properties map[string]any
+			Geometry   struct {
+				Type        string
+				Coordinates []float64
+			}
+		}
+	}
+	if err := json.Unmarshal(buf, &collection); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("PointsToGeoJSON() = %s", buf)
+	}
+	first := collection.Features[0]
+	if first.Properties["name"] != "Berlin" || first.Geometry.Type != "Point" || first.Geometry.Coordinates[0] != 13.405 || first.Geometry.Coordinates[1] != 52.52 {
+		t.Errorf("first feature = %+v", first)
+	}
+	if collection.Features[1].Properties == nil {
+		t.Error("second feature: expected empty properties object")
+	}
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"math"
+	"strings"
 
 	"github.com/flopp/go-coordsparser"
 )
@@ -19,14 +21,13 @@ func ParseLatLon(input string) (LatLon, error) {
 	return LatLon{Lat: lat, Lon: lon}, nil
 }
 
+const earthRadiusKM float64 = 6371.0
+
 func deg2rad(d float64) float64 {
 	return d * math.Pi / 180.0
 }
 
 func Distance(aa LatLon, bb LatLon) float64 {
-
-	const earthRadiusKM float64 = 6371.0
-
 	lat1 := deg2rad(aa.Lat)
 	lon1 := deg2rad(aa.Lon)
 	lat2 := deg2rad(bb.Lat)
@@ -40,3 +41,11 @@ func Distance(aa LatLon, bb LatLon) float64 {
 
 	return distance
 }
+
+// FormatDistance formats a distance in km for display, e.g. "3,4 km" or "27 km".
+func FormatDistance(km float64) string {
+	if math.Round(km*10) < 100 {
+		return strings.Replace(fmt.Sprintf("%.1f km", km), ".", ",", 1)
+	}
+	return fmt.Sprintf("%.0f km", km)
+}
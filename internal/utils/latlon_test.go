@@ -152,3 +152,23 @@ func TestDeg2Rad(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatDistance(t *testing.T) {
+	tests := []struct {
+		km       float64
+		expected string
+	}{
+		{0, "0,0 km"},
+		{3.44, "3,4 km"},
+		{9.96, "10 km"},
+		{10, "10 km"},
+		{27.5, "28 km"},
+		{123.4, "123 km"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatDistance(tt.km); result != tt.expected {
+			t.Errorf("FormatDistance(%f) = %q, want %q", tt.km, result, tt.expected)
+		}
+	}
+}
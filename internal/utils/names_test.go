@@ -2,6 +2,7 @@ package utils
 
 import (
 	"testing"
+	"time"
 )
 
 func TestSanitizeName(t *testing.T) {
@@ -76,3 +77,39 @@ func TestSplitAndTrim(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		n        int
+		expected string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1.000"},
+		{52300, "52.300"},
+		{3662000, "3.662.000"},
+		{-1234, "-1.234"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatNumber(tt.n); result != tt.expected {
+			t.Errorf("FormatNumber(%d) = %q, want %q", tt.n, result, tt.expected)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	for date, expected := range map[string]string{
+		"2025-03-03": "3. März 2025",
+		"2026-10-18": "18. Oktober 2026",
+		"2024-12-31": "31. Dezember 2024",
+	} {
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := FormatDate(d); result != expected {
+			t.Errorf("FormatDate(%s) = %q, want %q", date, result, expected)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// point3 is a location on the unit sphere; the euclidean (chord) distance of two
+// points grows monotonically with their great-circle distance, which lets the
+// k-d tree prune subtrees with simple coordinate comparisons.
+type point3 [3]float64
+
+func toPoint3(p LatLon) point3 {
+	lat := deg2rad(p.Lat)
+	lon := deg2rad(p.Lon)
+	return point3{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func chordDistance2(a, b point3) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// kmToChord converts a great-circle distance into the corresponding chord length.
+func kmToChord(km float64) float64 {
+	return 2 * math.Sin(math.Min(km/(2*earthRadiusKM), math.Pi/2))
+}
+
+type kdNode struct {
+	point       point3
+	item        int // index into SpatialIndex.items
+	axis        int
+	left, right int // node indices; -1 if missing
+}
+
+// Neighbour is a result of a spatial query.
+type Neighbour[T any] struct {
+	Item     T
+	Distance float64 // km
+}
+
+// SpatialIndex is a k-d tree over items with a location, answering
+// k-nearest-neighbour and within-radius queries.
+type SpatialIndex[T any] struct {
+	items     []T
+	locations []LatLon
+	nodes     []kdNode
+	root      int
+}
+
+// NewSpatialIndex builds an index of the items; location returns the
+// coordinates of an item, or nil if the item should not be indexed.
+func NewSpatialIndex[T any](items []T, location func(T) *LatLon) *SpatialIndex[T] {
+	index := &SpatialIndex[T]{root: -1}
+	for _, item := range items {
+		if p := location(item); p != nil {
+			index.items = append(index.items, item)
+			index.locations = append(index.locations, *p)
+		}
+	}
+
+	nodes := make([]kdNode, len(index.items))
+	for i, p := range index.locations {
+		nodes[i] = kdNode{point: toPoint3(p), item: i, left: -1, right: -1}
+	}
+	index.nodes = make([]kdNode, 0, len(nodes))
+	index.root = index.build(nodes)
+	return index
+}
+
+// build adds the subtree of nodes to the index and returns the root's index.
+func (index *SpatialIndex[T]) build(nodes []kdNode) int {
+	if len(nodes) == 0 {
+		return -1
+	}
+
+	// split along the axis with the largest spread
+	axis := 0
+	maxSpread := -1.0
+	for a := 0; a < 3; a++ {
+		lo, hi := nodes[0].point[a], nodes[0].point[a]
+		for _, n := range nodes[1:] {
+			lo = math.Min(lo, n.point[a])
+			hi = math.Max(hi, n.point[a])
+		}
+		if hi-lo > maxSpread {
+			axis, maxSpread = a, hi-lo
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].point[axis] < nodes[j].point[axis]
+	})
+
+	median := len(nodes) / 2
+	node := nodes[median]
+	node.axis = axis
+	index.nodes = append(index.nodes, node)
+	i := len(index.nodes) - 1
+	left := index.build(nodes[:median])
+	right := index.build(nodes[median+1:])
+	index.nodes[i].left = left
+	index.nodes[i].right = right
+	return i
+}
+
+func (index *SpatialIndex[T]) Len() int {
+	return len(index.items)
+}
+
+// candidate is an indexed item with its squared chord distance to the query point.
+type candidate struct {
+	item      int
+	distance2 float64
+}
+
+func (a candidate) less(b candidate) bool {
+	if a.distance2 != b.distance2 {
+		return a.distance2 < b.distance2
+	}
+	return a.item < b.item
+}
+
+// Nearest returns up to k items closest to p that are accepted by filter (all
+// items if filter is nil), sorted by increasing distance.
+func (index *SpatialIndex[T]) Nearest(p LatLon, k int, filter func(T) bool) []Neighbour[T] {
+	if k <= 0 {
+		return nil
+	}
+	q := toPoint3(p)
+	best := make([]candidate, 0, k)
+	var search func(n int)
+	search = func(n int) {
+		if n < 0 {
+			return
+		}
+		node := &index.nodes[n]
+		if filter == nil || filter(index.items[node.item]) {
+			c := candidate{node.item, chordDistance2(q, node.point)}
+			if len(best) < k || c.less(best[len(best)-1]) {
+				pos := sort.Search(len(best), func(i int) bool { return c.less(best[i]) })
+				if len(best) < k {
+					best = append(best, candidate{})
+				}
+				copy(best[pos+1:], best[pos:])
+				best[pos] = c
+			}
+		}
+
+		diff := q[node.axis] - node.point[node.axis]
+		near, far := node.left, node.right
+		if diff > 0 {
+			near, far = far, near
+		}
+		search(near)
+		if len(best) < k || diff*diff <= best[len(best)-1].distance2 {
+			search(far)
+		}
+	}
+	search(index.root)
+	return index.neighbours(p, best)
+}
+
+// WithinRadius returns all items accepted by filter (all items if filter is
+// nil) within radiusKM of p, sorted by increasing distance.
+func (index *SpatialIndex[T]) WithinRadius(p LatLon, radiusKM float64, filter func(T) bool) []Neighbour[T] {
+	if radiusKM < 0 {
+		return nil
+	}
+	q := toPoint3(p)
+	chord := kmToChord(radiusKM)
+	maxDistance2 := chord * chord
+	found := make([]candidate, 0)
+	var search func(n int)
+	search = func(n int) {
+		if n < 0 {
+			return
+		}
+		node := &index.nodes[n]
+		if d2 := chordDistance2(q, node.point); d2 <= maxDistance2 && (filter == nil || filter(index.items[node.item])) {
+			found = append(found, candidate{node.item, d2})
+		}
+		diff := q[node.axis] - node.point[node.axis]
+		if diff <= chord {
+			search(node.left)
+		}
+		if diff >= -chord {
+			search(node.right)
+		}
+	}
+	search(index.root)
+	sort.Slice(found, func(i, j int) bool { return found[i].less(found[j]) })
+
+	// the chord comparison may include items right at the border due to rounding
+	result := index.neighbours(p, found)
+	for len(result) > 0 && result[len(result)-1].Distance > radiusKM {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+func (index *SpatialIndex[T]) neighbours(p LatLon, candidates []candidate) []Neighbour[T] {
+	result := make([]Neighbour[T], 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, Neighbour[T]{index.items[c.item], Distance(p, index.locations[c.item])})
+	}
+	return result
+}
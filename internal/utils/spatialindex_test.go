@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type testPlace struct {
+	id     int
+	latLon *LatLon
+}
+
+// randomPlaces returns n places scattered over Germany; every tenth place has no location.
+func randomPlaces(n int, seed int64) []*testPlace {
+	r := rand.New(rand.NewSource(seed))
+	places := make([]*testPlace, 0, n)
+	for i := 0; i < n; i++ {
+		place := &testPlace{id: i}
+		if i%10 != 9 {
+			place.latLon = &LatLon{Lat: 47.3 + r.Float64()*7.7, Lon: 5.9 + r.Float64()*9.1}
+		}
+		places = append(places, place)
+	}
+	return places
+}
+
+func placeLocation(p *testPlace) *LatLon {
+	return p.latLon
+}
+
+// bruteForce returns all located places accepted by filter, sorted by distance to p.
+func bruteForce(places []*testPlace, p LatLon, filter func(*testPlace) bool) []Neighbour[*testPlace] {
+	result := make([]Neighbour[*testPlace], 0)
+	for _, place := range places {
+		if place.latLon != nil && (filter == nil || filter(place)) {
+			result = append(result, Neighbour[*testPlace]{place, Distance(p, *place.latLon)})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Distance < result[j].Distance
+	})
+	return result
+}
+
+func sameNeighbours(t *testing.T, name string, got, want []Neighbour[*testPlace]) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %d results, want %d", name, len(got), len(want))
+		return
+	}
+	for i := range got {
+		if got[i].Item != want[i].Item && got[i].Distance != want[i].Distance {
+			t.Errorf("%s: result %d = %d (%f km), want %d (%f km)", name, i, got[i].Item.id, got[i].Distance, want[i].Item.id, want[i].Distance)
+		}
+	}
+}
+
+func TestSpatialIndex(t *testing.T) {
+	places := randomPlaces(500, 1)
+	index := NewSpatialIndex(places, placeLocation)
+	if index.Len() != 450 {
+		t.Errorf("Len() = %d, want 450", index.Len())
+	}
+
+	even := func(p *testPlace) bool { return p.id%2 == 0 }
+	for _, query := range randomPlaces(50, 2) {
+		if query.latLon == nil {
+			continue
+		}
+		p := *query.latLon
+
+		for _, k := range []int{1, 3, 10} {
+			want := bruteForce(places, p, nil)[:k]
+			sameNeighbours(t, "Nearest", index.Nearest(p, k, nil), want)
+			want = bruteForce(places, p, even)[:k]
+			sameNeighbours(t, "Nearest with filter", index.Nearest(p, k, even), want)
+		}
+
+		for _, radius := range []float64{0, 10, 50, 200} {
+			want := make([]Neighbour[*testPlace], 0)
+			for _, n := range bruteForce(places, p, nil) {
+				if n.Distance <= radius {
+					want = append(want, n)
+				}
+			}
+			sameNeighbours(t, "WithinRadius", index.WithinRadius(p, radius, nil), want)
+		}
+	}
+}
+
+func TestSpatialIndexEdgeCases(t *testing.T) {
+	empty := NewSpatialIndex([]*testPlace{}, placeLocation)
+	if result := empty.Nearest(LatLon{Lat: 52.5, Lon: 13.4}, 3, nil); len(result) != 0 {
+		t.Errorf("Nearest() of empty index = %v", result)
+	}
+	if result := empty.WithinRadius(LatLon{Lat: 52.5, Lon: 13.4}, 100, nil); len(result) != 0 {
+		t.Errorf("WithinRadius() of empty index = %v", result)
+	}
+
+	places := randomPlaces(20, 3)
+	index := NewSpatialIndex(places, placeLocation)
+	if result := index.Nearest(LatLon{Lat: 52.5, Lon: 13.4}, 0, nil); len(result) != 0 {
+		t.Errorf("Nearest(k=0) = %v", result)
+	}
+	if result := index.Nearest(LatLon{Lat: 52.5, Lon: 13.4}, 100, nil); len(result) != index.Len() {
+		t.Errorf("Nearest(k=100) returned %d results, want %d", len(result), index.Len())
+	}
+	// the whole earth
+	if result := index.WithinRadius(LatLon{Lat: -40, Lon: -170}, 30000, nil); len(result) != index.Len() {
+		t.Errorf("WithinRadius(30000 km) returned %d results, want %d", len(result), index.Len())
+	}
+}
+
+func benchmarkPlaces(b *testing.B, n int) ([]*testPlace, []LatLon) {
+	b.Helper()
+	places := randomPlaces(n, 4)
+	queries := make([]LatLon, 0)
+	for _, q := range randomPlaces(100, 5) {
+		if q.latLon != nil {
+			queries = append(queries, *q.latLon)
+		}
+	}
+	return places, queries
+}
+
+func BenchmarkNewSpatialIndex(b *testing.B) {
+	places, _ := benchmarkPlaces(b, 2000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewSpatialIndex(places, placeLocation)
+	}
+}
+
+func BenchmarkNearest(b *testing.B) {
+	places, queries := benchmarkPlaces(b, 2000)
+	index := NewSpatialIndex(places, placeLocation)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Nearest(queries[i%len(queries)], 3, nil)
+	}
+}
+
+func BenchmarkNearestBruteForce(b *testing.B) {
+	places, queries := benchmarkPlaces(b, 2000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = bruteForce(places, queries[i%len(queries)], nil)[:3]
+	}
+}
+
+func BenchmarkWithinRadius(b *testing.B) {
+	places, queries := benchmarkPlaces(b, 2000)
+	index := NewSpatialIndex(places, placeLocation)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.WithinRadius(queries[i%len(queries)], 25, nil)
+	}
+}
@@ -16,6 +16,36 @@ type TemplateData interface {
 
 var templates = make(map[string]*template.Template)
 
+// BasePathFunc returns the "BasePath" template function: site paths stay as
+// they are for the remote target, while local builds get absolute file paths
+// (with "index.html") to be browsable without a web server.
+func BasePathFunc(data TemplateData) func(string) string {
+	return func(p string) string {
+		baseName := filepath.Base(p)
+		hasExtension := strings.Contains(baseName, ".")
+
+		if data.IsRemoteTarget() {
+			if !hasExtension && !strings.HasSuffix(p, "/") {
+				return p + "/"
+			}
+			return p
+		}
+
+		res := data.BasePath()
+		if !strings.HasPrefix(p, "/") {
+			res += "/"
+		}
+		res += p
+
+		if strings.HasSuffix(p, "/") {
+			res += "index.html"
+		} else if !hasExtension {
+			res += "/index.html"
+		}
+		return res
+	}
+}
+
 func loadTemplate(name string, data TemplateData) (*template.Template, error) {
 	templateName := filepath.Base(name)
 
@@ -33,30 +63,7 @@ func loadTemplate(name string, data TemplateData) (*template.Template, error) {
 	files = append(files, fmt.Sprintf("templates/%s", name))
 	files = append(files, parts...)
 	t, err := template.New(templateName).Funcs(template.FuncMap{
-		"BasePath": func(p string) string {
-			baseName := filepath.Base(p)
-			hasExtension := strings.Contains(baseName, ".")
-
-			if data.IsRemoteTarget() {
-				if !hasExtension && !strings.HasSuffix(p, "/") {
-					return p + "/"
-				}
-				return p
-			}
-
-			res := data.BasePath()
-			if !strings.HasPrefix(p, "/") {
-				res += "/"
-			}
-			res += p
-
-			if strings.HasSuffix(p, "/") {
-				res += "index.html"
-			} else if !hasExtension {
-				res += "/index.html"
-			}
-			return res
-		},
+		"BasePath": BasePathFunc(data),
 	}).ParseFiles(files...)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,5 @@
 document.addEventListener('DOMContentLoaded', function() {
     // CONSTANTS
-    const MAX_CITY_SEARCH_RESULTS = 5;
     const GERMANY_BOUNDS = [
         [50.913868, 5.8],
         [55.1, 8.041992],
@@ -16,7 +15,6 @@ document.addEventListener('DOMContentLoaded', function() {
     };
 
     const getClubText = (count) => count === 1 ? 'Club' : 'Clubs';
-    const getSocialRunClubText = (count) => count === 1 ? 'Social Run Club' : 'Social Run Clubs';
 
     const fixLeafletButtons = (div) => {
         div.querySelectorAll('[role="button"]').forEach((btn) => {
@@ -35,60 +33,209 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
-    // CITY SEARCH
-    const citySearchInput = document.getElementById('city-search-input');
-    if (citySearchInput) {
-        const citySearchResults = document.getElementById('city-search-results');
+    // CLUB FACETS: clubs match a facet (e.g. "day") if they have one of its
+    // checked values, and are shown if they match all facets; the checked
+    // values are kept in the URL query, e.g. "?day=dienstag,donnerstag&tag=trail"
+    const facetsDiv = document.querySelector('[data-club-facets]');
+    if (facetsDiv) {
+        const clubs = Array.from(document.querySelectorAll('[data-facets]')).map((el) => ({
+            el: el,
+            facets: new Set(el.dataset.facets.split(' '))
+        }));
+        const inputs = Array.from(facetsDiv.querySelectorAll('input[type="checkbox"]'));
+        const facetKeys = new Set(inputs.map((input) => input.name));
+        const resultEl = facetsDiv.querySelector('[data-facet-result]');
 
-        const addNotFoundResult = () => {
-            const liEl = createElement('li', { textContent: 'Keine passende Stadt gefunden' });
-            citySearchResults.appendChild(liEl);
+        const getSelection = () => {
+            const selection = {};
+            inputs.filter((input) => input.checked).forEach((input) => {
+                (selection[input.name] = selection[input.name] || []).push(input.value);
+            });
+            return selection;
         };
+        // skipKey ignores the selection of a facet, e.g. for counting its own options
+        const matches = (club, selection, skipKey) => Object.entries(selection).every(([key, values]) =>
+            key === skipKey || values.some((value) => club.facets.has(`${key}:${value}`)));
+
+        const applyFacets = (updateUrl) => {
+            const selection = getSelection();
+            let visible = 0;
+            clubs.forEach((club) => {
+                const match = matches(club, selection);
+                club.el.classList.toggle('filtered-out', !match);
+                if (match) {
+                    visible++;
+                }
+            });
+            inputs.forEach((input) => {
+                const count = clubs.filter((club) => club.facets.has(`${input.name}:${input.value}`) && matches(club, selection, input.name)).length;
+                input.parentElement.querySelector('[data-facet-count]').textContent = `(${count})`;
+                input.parentElement.classList.toggle('facet-empty', count === 0 && !input.checked);
+            });
+            resultEl.textContent = Object.keys(selection).length === 0 ? '' : `${visible} von ${clubs.length} ${getClubText(clubs.length)}`;
 
-        const addMoreResultsHint = (count) => {
-            const liEl = createElement('li', { textContent: `... und ${count} weitere Städte` });
-            citySearchResults.appendChild(liEl);
+            if (updateUrl) {
+                const params = new URLSearchParams(window.location.search);
+                facetKeys.forEach((key) => params.delete(key));
+                Object.entries(selection).forEach(([key, values]) => params.set(key, values.join(',')));
+                const query = params.toString().replace(/%2C/g, ',');
+                window.history.replaceState(null, '', window.location.pathname + (query ? `?${query}` : '') + window.location.hash);
+            }
         };
 
-        const addResult = (city) => {
-            const [cityName, cityUrl, cityClubs] = city;
-            const clubText = getSocialRunClubText(cityClubs);
-            const liEl = document.createElement('li');
-            const aEl = createElement('a', {
-                href: cityUrl,
-                textContent: `${cityName} (${cityClubs} ${clubText})`,
-                className: 'city-search-result'
+        const params = new URLSearchParams(window.location.search);
+        inputs.forEach((input) => {
+            const values = (params.get(input.name) || '').split(',');
+            input.checked = values.includes(input.value);
+            if (input.checked) {
+                facetsDiv.open = true;
+            }
+        });
+        facetsDiv.addEventListener('change', () => applyFacets(true));
+        facetsDiv.querySelector('[data-facet-reset]').addEventListener('click', (event) => {
+            event.preventDefault();
+            inputs.forEach((input) => {
+                input.checked = false;
             });
-            liEl.appendChild(aEl);
-            citySearchResults.appendChild(liEl);
-        };
+            applyFacets(true);
+        });
+        applyFacets(false);
+    }
 
-        citySearchInput.addEventListener('input', function() {
-            const filter = citySearchInput.value.toLowerCase().trim();
-            if (filter === '') {
-                citySearchResults.style.display = 'none';
-                citySearchResults.innerHTML = '';
-                return;
+    // TODAY: show today's runs first, followed by the rest of the week
+    const todayDiv = document.querySelector('[data-today]');
+    if (todayDiv) {
+        const today = new Date().getDay();
+        const sections = Array.from(todayDiv.querySelectorAll('[data-weekday]'));
+        sections.sort((a, b) => (parseInt(a.dataset.weekday, 10) - today + 7) % 7 - (parseInt(b.dataset.weekday, 10) - today + 7) % 7);
+        sections.forEach((section) => {
+            if (parseInt(section.dataset.weekday, 10) === today) {
+                section.querySelector('[data-today-label]').hidden = false;
             }
+            todayDiv.appendChild(section);
+        });
+    }
 
-            const results = cityData.filter(city => city[0].toLowerCase().includes(filter));
-            citySearchResults.innerHTML = '';
+    // SITE SEARCH
+    // folds the text like SanitizeName in Go (see searchTerms), e.g. "Köln" => ["koeln"]
+    const normalizeSearch = (text) => text.toLowerCase()
+        .replace(/ä/g, 'ae').replace(/ö/g, 'oe').replace(/ü/g, 'ue').replace(/ß/g, 'ss').replace(/ø/g, 'o')
+        .normalize('NFD').replace(/[\u0300-\u036f]/g, '')
+        .split(/[^a-z0-9]+/).filter(term => term !== '');
 
-            if (results.length === 0) {
-                addNotFoundResult();
-            } else {
-                const displayResults = results.length > MAX_CITY_SEARCH_RESULTS
-                    ? results.slice(0, MAX_CITY_SEARCH_RESULTS)
-                    : results;
-                displayResults.forEach(addResult);
-
-                if (results.length > MAX_CITY_SEARCH_RESULTS) {
-                    addMoreResultsHint(results.length - MAX_CITY_SEARCH_RESULTS);
-                }
+    const editDistance = (a, b) => {
+        let prev = Array.from({ length: b.length + 1 }, (_, i) => i);
+        for (let i = 1; i <= a.length; i++) {
+            const cur = [i];
+            for (let j = 1; j <= b.length; j++) {
+                cur[j] = Math.min(prev[j] + 1, cur[j - 1] + 1, prev[j - 1] + (a[i - 1] === b[j - 1] ? 0 : 1));
             }
-            citySearchResults.style.display = 'block';
+            prev = cur;
+        }
+        return prev[b.length];
+    };
+
+    // 3: exact, 2: prefix, 1: typo (short terms need an exact or prefix match), 0: no match
+    const matchTerm = (query, term) => {
+        if (term === query) return 3;
+        if (term.startsWith(query)) return 2;
+        if (query.length < 4) return 0;
+        const maxDistance = query.length >= 8 ? 2 : 1;
+        return Math.min(editDistance(query, term), editDistance(query, term.slice(0, query.length))) <= maxDistance ? 1 : 0;
+    };
+
+    const searchEntries = (entries, query) => {
+        const queryTerms = normalizeSearch(query);
+        if (queryTerms.length === 0) return [];
+        const queryName = queryTerms.join(' ');
+        const results = [];
+        entries.forEach(entry => {
+            let score = 0;
+            for (const queryTerm of queryTerms) {
+                const best = Math.max(0, ...entry.terms.map(term => matchTerm(queryTerm, term)));
+                if (best === 0) return;
+                score += best;
+            }
+            // prefer entries named like the query, e.g. the city "Berlin" over "Berlin Run Club"
+            const name = normalizeSearch(entry.name).join(' ');
+            if (name === queryName) {
+                score += 3;
+            } else if (name.startsWith(queryName)) {
+                score += 1;
+            }
+            results.push({ entry, score });
         });
-    }
+        results.sort((a, b) => b.score - a.score || b.entry.weight - a.entry.weight || a.entry.name.localeCompare(b.entry.name));
+        return results.map(result => result.entry);
+    };
+
+    let searchIndexEntries = null;
+    let searchIndexCallbacks = null; // callbacks waiting for the pending load
+    const loadSearchIndex = (src, callback) => {
+        if (searchIndexEntries !== null) {
+            callback(searchIndexEntries);
+            return;
+        }
+        if (searchIndexCallbacks !== null) {
+            searchIndexCallbacks.push(callback);
+            return;
+        }
+        searchIndexCallbacks = [callback];
+        const scriptEl = createElement('script', { src: src });
+        scriptEl.addEventListener('load', () => {
+            const index = window.searchIndex;
+            searchIndexEntries = index.e.map(([kind, name, url, detail, terms, weight]) => ({
+                kind: index.k[kind], name, url, detail, terms: terms.split(' '), weight
+            }));
+            const callbacks = searchIndexCallbacks;
+            searchIndexCallbacks = null;
+            callbacks.forEach(cb => cb(searchIndexEntries));
+        });
+        scriptEl.addEventListener('error', () => {
+            // allow another try with the next input
+            searchIndexCallbacks = null;
+            scriptEl.remove();
+        });
+        document.head.appendChild(scriptEl);
+    };
+
+    document.querySelectorAll('[data-site-search]').forEach((searchEl) => {
+        const input = searchEl.querySelector('input');
+        const resultsEl = searchEl.querySelector('ul');
+        const maxResults = parseInt(searchEl.dataset.maxResults || '8', 10);
+
+        const showResults = (entries) => {
+            const results = searchEntries(entries, input.value);
+            resultsEl.innerHTML = '';
+            if (input.value.trim() === '') {
+                resultsEl.style.display = 'none';
+                return;
+            }
+            if (results.length === 0) {
+                resultsEl.appendChild(createElement('li', { textContent: 'Leider nichts gefunden' }));
+            }
+            results.slice(0, maxResults).forEach((entry) => {
+                const liEl = document.createElement('li');
+                const aEl = createElement('a', { href: entry.url, className: 'site-search-result', textContent: entry.name });
+                liEl.appendChild(aEl);
+                liEl.appendChild(createElement('small', { textContent: ` ${entry.kind} · ${entry.detail}` }));
+                resultsEl.appendChild(liEl);
+            });
+            if (results.length > maxResults) {
+                resultsEl.appendChild(createElement('li', { textContent: `... und ${results.length - maxResults} weitere Treffer` }));
+            }
+            resultsEl.style.display = 'block';
+        };
+
+        const search = () => loadSearchIndex(searchEl.dataset.index, showResults);
+        input.addEventListener('input', search);
+        // the search page takes the query from the URL, e.g. /suche.html?q=koeln
+        const query = new URLSearchParams(window.location.search).get('q');
+        if (searchEl.closest('[data-url-query]') && query) {
+            input.value = query;
+            search();
+        }
+    });
 
     // MAPS
     const baseLayer = L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
@@ -106,6 +253,20 @@ document.addEventListener('DOMContentLoaded', function() {
         return L.marker([lat, lon]).bindPopup(popupContent);
     };
 
+    // A pre-rendered map image (see static-map.html) is replaced by the
+    // interactive map on click, so no tiles are loaded before.
+    const whenMapRequested = (mapDiv, init) => {
+        const button = mapDiv.querySelector('[data-load-map]');
+        if (!button) {
+            init();
+            return;
+        }
+        button.addEventListener('click', () => {
+            mapDiv.replaceChildren();
+            init();
+        }, { once: true });
+    };
+
     // Club Map
     const clubMapDiv = document.getElementById('club-map');
     if (clubMapDiv) {
@@ -122,9 +283,11 @@ document.addEventListener('DOMContentLoaded', function() {
             popup += `<br><i>${cityName}</i>`;
         }
 
-        const map = initializeMap('club-map').setView([lat, lon], 13);
-        createMarker(lat, lon, popup).addTo(map).openPopup();
-        fixLeafletButtons(clubMapDiv);
+        whenMapRequested(clubMapDiv, () => {
+            const map = initializeMap('club-map').setView([lat, lon], 13);
+            createMarker(lat, lon, popup).addTo(map).openPopup();
+            fixLeafletButtons(clubMapDiv);
+        });
     }
 
     // City Map
@@ -134,16 +297,19 @@ document.addEventListener('DOMContentLoaded', function() {
         const lon = parseFloat(cityMapDiv.dataset.lon);
         const name = cityMapDiv.dataset.name;
 
-        const map = initializeMap('city-map');
-        createMarker(lat, lon, name).addTo(map).openPopup();
-        map.fitBounds(GERMANY_BOUNDS);
-        fixLeafletButtons(cityMapDiv);
+        whenMapRequested(cityMapDiv, () => {
+            const map = initializeMap('city-map');
+            createMarker(lat, lon, name).addTo(map).openPopup();
+            map.fitBounds(GERMANY_BOUNDS);
+            fixLeafletButtons(cityMapDiv);
+        });
     }
 
     // Cities Map
     const citiesMapDiv = document.getElementById('cities-map');
     if (citiesMapDiv) {
         const markers = L.layerGroup();
+        const bounds = L.latLngBounds([]);
         document.querySelectorAll('[data-search]').forEach(function(cityEl) {
             const city = {
                 url: cityEl.dataset.url,
@@ -152,12 +318,22 @@ document.addEventListener('DOMContentLoaded', function() {
                 lat: cityEl.dataset.lat,
                 lon: cityEl.dataset.lon
             };
+            if (city.lat === undefined || city.lon === undefined) {
+                return;
+            }
             const clubText = getClubText(city.clubs);
             const popup = `<a href="${city.url}">${city.name}</a> (${city.clubs} ${clubText})`;
             const marker = createMarker(city.lat, city.lon, popup);
             markers.addLayer(marker);
+            bounds.extend([city.lat, city.lon]);
         });
-        const map = initializeMap('cities-map', { layers: [baseLayer, markers] }).fitBounds(GERMANY_BOUNDS);
+        const map = initializeMap('cities-map', { layers: [baseLayer, markers] });
+        // data-fit: zoom to the markers (e.g. of a state) instead of all of Germany
+        if (citiesMapDiv.dataset.fit !== undefined && bounds.isValid()) {
+            map.fitBounds(bounds.pad(0.2), { maxZoom: 10 });
+        } else {
+            map.fitBounds(GERMANY_BOUNDS);
+        }
         fixLeafletButtons(citiesMapDiv);
     }
 
@@ -172,6 +348,9 @@ document.addEventListener('DOMContentLoaded', function() {
                 lon: parseFloat(clubEl.dataset.lon),
                 url: clubEl.dataset.url
             };
+            if (isNaN(club.lat) || isNaN(club.lon)) {
+                return;
+            }
             const popup = `<a href="${club.url}">${club.name}</a>`;
             const marker = createMarker(club.lat, club.lon, popup);
             markers.addLayer(marker);
@@ -193,6 +372,32 @@ document.addEventListener('DOMContentLoaded', function() {
         fixLeafletButtons(clusterMapDiv);
     }
 
+    // Coverage Map
+    const coverageMapDiv = document.getElementById('coverage-map');
+    if (coverageMapDiv) {
+        const markers = L.layerGroup();
+        document.querySelectorAll('[data-gap]').forEach(function(gapEl) {
+            const popup = `<b>${gapEl.dataset.name}</b><br>${gapEl.dataset.population} Einwohner:innen`;
+            const marker = L.circleMarker([parseFloat(gapEl.dataset.lat), parseFloat(gapEl.dataset.lon)], { radius: 6, color: '#d9534f' }).bindPopup(popup);
+            markers.addLayer(marker);
+        });
+        initializeMap('coverage-map', { layers: [baseLayer, markers] }).fitBounds(GERMANY_BOUNDS);
+        fixLeafletButtons(coverageMapDiv);
+    }
+
+    // Neighbourhood filter
+    const neighbourhoodFilter = document.querySelector('[data-neighbourhood-filter]');
+    if (neighbourhoodFilter) {
+        const applyNeighbourhoodFilter = () => {
+            const selected = neighbourhoodFilter.value;
+            document.querySelectorAll('[data-neighbourhood]').forEach(function(section) {
+                section.style.display = (selected === '' || section.dataset.neighbourhood === selected) ? '' : 'none';
+            });
+        };
+        neighbourhoodFilter.addEventListener('change', applyNeighbourhoodFilter);
+        applyNeighbourhoodFilter();
+    }
+
     // SHARE
     document.querySelectorAll('[data-share]').forEach(function(shareBtn) {
         const url = shareBtn.dataset.url || window.location.href;